@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xiaopal/kube-informer/pkg/informer"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const handlerOutputJSON = "json"
+
+// handlerAction is returned by handlers on stdout and by webhooks in response body, with --handler-output json
+type handlerAction struct {
+	// RequeueAfter handles the object again after the delay, in seconds or as duration
+	RequeueAfter json.RawMessage `json:"requeueAfter,omitempty"`
+	// Patch is a merge patch to the object
+	Patch json.RawMessage `json:"patch,omitempty"`
+	// Annotations are set to the object, null to remove
+	Annotations map[string]*string `json:"annotations,omitempty"`
+	// Event is recorded for the object
+	Event *struct {
+		Type    string `json:"type"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	} `json:"event,omitempty"`
+}
+
+func parseDelay(val string) (time.Duration, bool) {
+	val = strings.TrimSpace(val)
+	if seconds, err := strconv.Atoi(val); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if delay, err := time.ParseDuration(val); err == nil {
+		return delay, true
+	}
+	return 0, false
+}
+
+// handleOutput carries out the action document of the output
+func handleOutput(obj *unstructured.Unstructured, output []byte, logger *log.Logger) error {
+	if handlerOutput != handlerOutputJSON || len(bytes.TrimSpace(output)) == 0 {
+		return nil
+	}
+	action := &handlerAction{}
+	if err := json.Unmarshal(output, action); err != nil {
+		return fmt.Errorf("failed to parse handler output: %v", err)
+	}
+	return action.apply(obj, logger)
+}
+
+// apply carries out the action, the result is RequeueAfter if requested
+func (action *handlerAction) apply(obj *unstructured.Unstructured, logger *log.Logger) error {
+	if len(action.Patch) > 0 && string(action.Patch) != "null" {
+		if err := patchObject(obj, action.Patch, logger); err != nil {
+			return fmt.Errorf("failed to patch: %v", err)
+		}
+	}
+	if len(action.Annotations) > 0 {
+		patch, _ := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": action.Annotations}})
+		if err := patchObject(obj, patch, logger); err != nil {
+			return fmt.Errorf("failed to set annotations: %v", err)
+		}
+	}
+	if event := action.Event; event != nil {
+		eventType := event.Type
+		if eventType == "" {
+			eventType = "Normal"
+		}
+		if err := kubeClient.RecordEvent(obj, eventType, event.Reason, event.Message); err != nil {
+			return fmt.Errorf("failed to record event: %v", err)
+		}
+	}
+	if len(action.RequeueAfter) > 0 {
+		val := string(action.RequeueAfter)
+		if unquoted, err := strconv.Unquote(val); err == nil {
+			val = unquoted
+		}
+		delay, ok := parseDelay(val)
+		if !ok {
+			return fmt.Errorf("invalid requeueAfter %s", action.RequeueAfter)
+		}
+		return informer.RequeueAfter(delay)
+	}
+	return nil
+}
+
+// patchObject ignores objects already deleted
+func patchObject(obj *unstructured.Unstructured, patch []byte, logger *log.Logger) error {
+	if _, err := kubeClient.PatchObject(obj, types.MergePatchType, patch); err != nil {
+		if errors.IsNotFound(err) {
+			logger.Printf("ignored patch to %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/xiaopal/kube-informer/pkg/informer"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseDelay(t *testing.T) {
+	tests := []struct {
+		val  string
+		want time.Duration
+		ok   bool
+	}{
+		{"30", 30 * time.Second, true},
+		{" 30\n", 30 * time.Second, true},
+		{"0", 0, true},
+		{"90s", 90 * time.Second, true},
+		{"1m30s", 90 * time.Second, true},
+		{"1.5h", 90 * time.Minute, true},
+		{"", 0, false},
+		{"1.5", 0, false},
+		{"30 s", 0, false},
+		{"soon", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := parseDelay(test.val)
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseDelay(%q) = %v, %v, want %v, %v", test.val, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestHandleOutputRequeueAfter(t *testing.T) {
+	defer func(output string) { handlerOutput = output }(handlerOutput)
+	handlerOutput = handlerOutputJSON
+	logger := log.New(ioutil.Discard, "", 0)
+	tests := []struct {
+		output string
+		want   time.Duration
+		ok     bool
+	}{
+		{`{"requeueAfter": 30}`, 30 * time.Second, true},
+		{`{"requeueAfter": "2m"}`, 2 * time.Minute, true},
+		{`{}`, 0, false},
+		{``, 0, false},
+	}
+	for _, test := range tests {
+		err := handleOutput(&unstructured.Unstructured{}, []byte(test.output), logger)
+		if delay, ok := informer.RequeueDelay(err); delay != test.want || ok != test.ok {
+			t.Errorf("handleOutput(%s) = %v, want requeue after %v", test.output, err, test.want)
+		}
+	}
+	for _, output := range []string{`{"requeueAfter": "soon"}`, `{"requeueAfter": true}`, `not json`} {
+		if err := handleOutput(&unstructured.Unstructured{}, []byte(output), logger); !handlerFailed(err) {
+			t.Errorf("handleOutput(%s) = %v, want failed", output, err)
+		}
+	}
+}
+
+func TestExecuteWebhooksAfterRequeue(t *testing.T) {
+	defer func(output string, hooks []*url.URL) { handlerOutput, webhooks = output, hooks }(handlerOutput, webhooks)
+	handlerOutput, webhooks = handlerOutputJSON, nil
+	called := []string{}
+	for _, res := range []string{`{"requeueAfter": 60}`, `{"requeueAfter": 30}`, `{}`} {
+		res := res
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = append(called, res)
+			w.Write([]byte(res))
+		}))
+		defer server.Close()
+		webhook, _ := url.Parse(server.URL)
+		webhooks = append(webhooks, webhook)
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	payload, _ := newEventPayload(informer.EventAdd, obj, nil)
+	err := executeWebhooks(context.Background(), informer.EventAdd, obj, []byte("{}"), payload, 0, log.New(ioutil.Discard, "", 0))
+	if len(called) != 3 {
+		t.Errorf("called %d webhooks, want all 3", len(called))
+	}
+	if delay, ok := informer.RequeueDelay(err); !ok || delay != 30*time.Second {
+		t.Errorf("executeWebhooks() = %v, want requeue after 30s", err)
+	}
+}
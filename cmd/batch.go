@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/xiaopal/kube-informer/pkg/informer"
+	"github.com/xiaopal/kube-informer/pkg/subreaper"
+)
+
+// batchItem is an element of the json array passed to batch handlers
+type batchItem struct {
+	*eventPayload
+	Retries int `json:"retries,omitempty"`
+}
+
+// batchResult is the optional response of batch webhooks
+type batchResult struct {
+	Failed []int `json:"failed"`
+}
+
+func handleBatch(ctx context.Context, events []informer.Event) []error {
+	errs, items, itemsJSON, indexes := make([]error, len(events)), []batchItem{}, [][]byte{}, []int{}
+	for index, event := range events {
+		event.Type = handlerEvent(event.Type)
+		if !handlerAccepts(event.Type, event.Object) {
+			continue
+		}
+		payload, err := newEventPayload(event.Type, event.Object, event.OldObject)
+		if err != nil {
+			errs[index] = err
+			continue
+		}
+		// items are marshaled one by one, so that only the item failed to marshal fails
+		item := batchItem{payload, event.NumRetries}
+		itemJSON, err := json.Marshal(item)
+		if err != nil {
+			errs[index] = fmt.Errorf("failed to marshal obj: %v", err)
+			continue
+		}
+		items, itemsJSON, indexes = append(items, item), append(itemsJSON, itemJSON), append(indexes, index)
+	}
+	if len(items) == 0 {
+		return errs
+	}
+	data := append(append([]byte{'['}, bytes.Join(itemsJSON, []byte{','})...), ']')
+	logger := log.New(os.Stderr, fmt.Sprintf("[%s] ", handlerName), log.Flags())
+	fail := func(failed []int, err error) {
+		if err == nil {
+			return
+		}
+		if len(failed) == 0 {
+			for _, index := range indexes {
+				errs[index] = handlerResult(errs[index], err)
+			}
+			return
+		}
+		for _, item := range failed {
+			if item >= 0 && item < len(indexes) {
+				errs[indexes[item]] = handlerResult(errs[indexes[item]], err)
+			}
+		}
+	}
+	if len(handlerCommand) > 0 {
+		fail(executeBatchCommand(ctx, data, len(items), logger))
+	} else {
+		for _, item := range items {
+			obj := item.Object
+			logger.Printf("%s %s.%s: %s/%s", item.Event, obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName())
+		}
+	}
+	for _, webhook := range webhooks {
+		fail(executeBatchWebhook(ctx, webhook.String(), data, len(items), logger))
+	}
+	return errs
+}
+
+// executeBatchCommand pipes the batch to the handler stdin, the handler may write indexes of failed items
+// to the file $INFORMER_BATCH_FAILED, one per line, otherwise the exit status applies to all items
+func executeBatchCommand(ctx context.Context, data []byte, size int, logger *log.Logger) ([]int, error) {
+	failedFile, err := ioutil.TempFile("", "informer-batch-failed-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup handler: %v", err)
+	}
+	failedFile.Close()
+	defer os.Remove(failedFile.Name())
+	handler := exec.Command(handlerCommand[0], handlerCommand[1:]...)
+	handler.Env = append(os.Environ(),
+		fmt.Sprintf("INFORMER_BATCH_SIZE=%d", size),
+		fmt.Sprintf("INFORMER_BATCH_FAILED=%s", failedFile.Name()),
+		fmt.Sprintf("INFORMER_MAX_RETRIES=%d", handlerMaxRetries),
+	)
+	handler.Stdin = bytes.NewReader(data)
+	if err := pipeStderr(handler, logger); err != nil {
+		return nil, fmt.Errorf("failed to setup handler: failed to pipe stderr: %v", err)
+	}
+	handler.Stdout = os.Stdout
+	requeue, err := setupRequeue(handler)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup handler: %v", err)
+	}
+	defer requeue.remove()
+	subreaper.Pause()
+	runErr := runHandler(ctx, handler, logger)
+	subreaper.Resume()
+	if ok, result := handlerExitResult(runErr, requeue); ok {
+		return nil, result
+	}
+	failed, err := readFailedItems(failedFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read failed items: %v", err)
+	}
+	if runErr != nil {
+		return failed, fmt.Errorf("failed to execute handler: %v", runErr)
+	}
+	if len(failed) > 0 {
+		return failed, fmt.Errorf("marked failed by handler")
+	}
+	return nil, nil
+}
+
+func readFailedItems(path string) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ret, lines := []int{}, bufio.NewScanner(f)
+	for lines.Scan() {
+		if line := strings.TrimSpace(lines.Text()); line != "" {
+			index, err := strconv.Atoi(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid item index %s", line)
+			}
+			ret = append(ret, index)
+		}
+	}
+	return ret, lines.Err()
+}
+
+// executeBatchWebhook posts the batch, the response may be `{"failed":[<item indexes>]}`
+func executeBatchWebhook(ctx context.Context, webhook string, data []byte, size int, logger *log.Logger) ([]int, error) {
+	req, err := http.NewRequest("POST", webhook, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare webhook: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	reqCtx, endReq := context.WithTimeout(ctx, webhookTimeout)
+	defer endReq()
+	res, err := http.DefaultClient.Do(req.WithContext(reqCtx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to process webhook %s: %v", webhook, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to process webhook %s: HTTP %s", webhook, res.Status)
+	}
+	result := &batchResult{}
+	if body, err := ioutil.ReadAll(res.Body); err == nil && len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, result); err != nil && glog.V(2) {
+			logger.Printf("ignored webhook %s response: %v", webhook, err)
+		}
+	}
+	if glog.V(2) {
+		logger.Printf("triggerred webhook %s, batch of %d", webhook, size)
+	}
+	if len(result.Failed) > 0 {
+		return result.Failed, fmt.Errorf("marked failed by webhook %s", webhook)
+	}
+	return nil, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/xiaopal/kube-informer/pkg/informer"
+)
+
+// handlerRequeue is the file $INFORMER_REQUEUE_AFTER_FILE where handlers exiting with --exit-code-requeue
+// write the delay, in seconds or as duration
+type handlerRequeue struct {
+	file string
+}
+
+func setupRequeue(handler *exec.Cmd) (*handlerRequeue, error) {
+	if exitCodeRequeue == 0 {
+		return nil, nil
+	}
+	f, err := ioutil.TempFile("", "informer-requeue-after-")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	handler.Env = append(handler.Env, "INFORMER_REQUEUE_AFTER_FILE="+f.Name())
+	return &handlerRequeue{file: f.Name()}, nil
+}
+
+func (r *handlerRequeue) remove() {
+	if r != nil {
+		os.Remove(r.file)
+	}
+}
+
+func (r *handlerRequeue) delay() time.Duration {
+	data, _ := ioutil.ReadFile(r.file)
+	return requeueAfterDelay(data)
+}
+
+// requeueAfterDelay parses the delay written to the file, --requeue-after if missing or invalid
+func requeueAfterDelay(data []byte) time.Duration {
+	if delay, ok := parseDelay(string(data)); ok {
+		return delay
+	}
+	return requeueAfterDefault
+}
+
+// handlerExitResult maps the exit codes of --exit-code-skip, --exit-code-permanent and --exit-code-requeue
+func handlerExitResult(err error, requeue *handlerRequeue) (bool, error) {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false, err
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok {
+		return false, err
+	}
+	switch code := status.ExitStatus(); {
+	case exitCodeSkip != 0 && code == exitCodeSkip:
+		return true, informer.Skip()
+	case exitCodePermanent != 0 && code == exitCodePermanent:
+		return true, informer.PermanentError(err)
+	case exitCodeRequeue != 0 && code == exitCodeRequeue && requeue != nil:
+		return true, informer.RequeueAfter(requeue.delay())
+	}
+	return false, err
+}
+
+// handlerFailed is true for errors other than Skip and RequeueAfter
+func handlerFailed(err error) bool {
+	_, requeue := informer.RequeueDelay(err)
+	return err != nil && !requeue && !informer.IsSkip(err)
+}
+
+// handlerResult combines the results of the handlers of an event: the first failure,
+// otherwise the shortest requeue delay, otherwise skipped if any handler skipped
+func handlerResult(results ...error) error {
+	var requeue, skip error
+	for _, err := range results {
+		if handlerFailed(err) {
+			return err
+		}
+		if delay, ok := informer.RequeueDelay(err); ok {
+			if current, requeued := informer.RequeueDelay(requeue); !requeued || delay < current {
+				requeue = err
+			}
+		} else if informer.IsSkip(err) {
+			skip = err
+		}
+	}
+	if requeue != nil {
+		return requeue
+	}
+	return skip
+}
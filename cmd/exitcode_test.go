@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/xiaopal/kube-informer/pkg/informer"
+)
+
+func TestRequeueAfterDelay(t *testing.T) {
+	defer func(delay time.Duration) { requeueAfterDefault = delay }(requeueAfterDefault)
+	requeueAfterDefault = time.Minute
+	tests := []struct {
+		data string
+		want time.Duration
+	}{
+		{"30\n", 30 * time.Second},
+		{" 1m30s ", 90 * time.Second},
+		{"invalid", time.Minute},
+		{"INFORMER_REQUEUE_AFTER=5", time.Minute},
+		{"", time.Minute},
+	}
+	for _, test := range tests {
+		if got := requeueAfterDelay([]byte(test.data)); got != test.want {
+			t.Errorf("requeueAfterDelay(%q) = %v, want %v", test.data, got, test.want)
+		}
+	}
+}
+
+func TestHandlerExitResult(t *testing.T) {
+	defer func(skip, permanent, requeue int, delay time.Duration) {
+		exitCodeSkip, exitCodePermanent, exitCodeRequeue, requeueAfterDefault = skip, permanent, requeue, delay
+	}(exitCodeSkip, exitCodePermanent, exitCodeRequeue, requeueAfterDefault)
+	exitCodeSkip, exitCodePermanent, exitCodeRequeue, requeueAfterDefault = 12, 11, 10, time.Minute
+	tests := []struct {
+		script string
+		check  func(error) bool
+		mapped bool
+	}{
+		{"exit 12", informer.IsSkip, true},
+		{"exit 11", informer.IsPermanentError, true},
+		{"echo 30 >$INFORMER_REQUEUE_AFTER_FILE; exit 10", requeuedAfter(30 * time.Second), true},
+		{"echo 2m; exit 10", requeuedAfter(time.Minute), true},
+		{"exit 10", requeuedAfter(time.Minute), true},
+		{"exit 1", handlerFailed, false},
+	}
+	for _, test := range tests {
+		handler := exec.Command("sh", "-c", test.script)
+		requeue, err := setupRequeue(handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mapped, result := handlerExitResult(handler.Run(), requeue)
+		requeue.remove()
+		if mapped != test.mapped || !test.check(result) {
+			t.Errorf("handlerExitResult(%q) = %v, %v", test.script, mapped, result)
+		}
+	}
+	if err := errors.New("failed to start"); !handlerFailed(err) {
+		t.Errorf("handlerFailed(%v) = false", err)
+	}
+	if mapped, result := handlerExitResult(nil, nil); result != nil || mapped {
+		t.Errorf("handlerExitResult(nil) = %v, %v", mapped, result)
+	}
+}
+
+func requeuedAfter(want time.Duration) func(error) bool {
+	return func(err error) bool {
+		delay, ok := informer.RequeueDelay(err)
+		return ok && delay == want
+	}
+}
+
+func TestHandlerResult(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		results []error
+		check   func(error) bool
+	}{
+		{[]error{}, func(err error) bool { return err == nil }},
+		{[]error{nil, nil}, func(err error) bool { return err == nil }},
+		{[]error{informer.Skip(), nil}, informer.IsSkip},
+		{[]error{nil, informer.Skip()}, informer.IsSkip},
+		{[]error{informer.RequeueAfter(time.Minute), nil}, requeuedAfter(time.Minute)},
+		{[]error{informer.RequeueAfter(time.Minute), informer.RequeueAfter(time.Second)}, requeuedAfter(time.Second)},
+		{[]error{informer.Skip(), informer.RequeueAfter(time.Minute)}, requeuedAfter(time.Minute)},
+		{[]error{informer.RequeueAfter(time.Minute), failed}, func(err error) bool { return err == failed }},
+		{[]error{failed, informer.Skip()}, func(err error) bool { return err == failed }},
+	}
+	for _, test := range tests {
+		if got := handlerResult(test.results...); !test.check(got) {
+			t.Errorf("handlerResult(%v) = %v", test.results, got)
+		}
+	}
+}
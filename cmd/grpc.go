@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/xiaopal/kube-informer/pkg/grpchandler"
+	"github.com/xiaopal/kube-informer/pkg/informer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// executeGRPCHandler calls HandleEvent of --grpc-handler, acks map to the results of informer handlers
+func executeGRPCHandler(ctx context.Context, event informer.EventType, obj *unstructured.Unstructured, objJSON []byte, payload *eventPayload, numRetries int, logger *log.Logger) error {
+	if handlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, handlerTimeout)
+		defer cancel()
+	}
+	req := &grpchandler.EventRequest{
+		Event:           string(event),
+		ApiVersion:      obj.GetAPIVersion(),
+		Kind:            obj.GetKind(),
+		Namespace:       obj.GetNamespace(),
+		Name:            obj.GetName(),
+		ResourceVersion: obj.GetResourceVersion(),
+		Object:          objJSON,
+		OldObject:       payload.oldJSON,
+		Patch:           payload.Patch,
+		Retries:         int32(numRetries),
+		MaxRetries:      int32(handlerMaxRetries),
+	}
+	ack, err := grpcHandlerClient.HandleEvent(ctx, req)
+	if status.Code(err) == codes.DeadlineExceeded && handlerTimeout > 0 {
+		return fmt.Errorf("grpc handler timed out after %v", handlerTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to call grpc handler: %v", err)
+	}
+	if glog.V(2) {
+		logger.Printf("called grpc handler, %s %s.%s: %s/%s", event, obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	}
+	return grpcAckResult(ack)
+}
+
+// grpcAckResult maps the ack to the result of informer handlers
+func grpcAckResult(ack *grpchandler.EventAck) error {
+	switch {
+	case ack.Error != "" && ack.Permanent:
+		return informer.PermanentError(fmt.Errorf("grpc handler failed: %s", ack.Error))
+	case ack.Error != "":
+		return fmt.Errorf("grpc handler failed: %s", ack.Error)
+	case ack.Skip:
+		return informer.Skip()
+	case ack.RequeueAfter != nil:
+		delay, err := ptypes.Duration(ack.RequeueAfter)
+		if err != nil {
+			return fmt.Errorf("invalid requeue_after: %v", err)
+		}
+		return informer.RequeueAfter(delay)
+	}
+	return nil
+}
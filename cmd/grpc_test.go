@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/xiaopal/kube-informer/pkg/grpchandler"
+	"github.com/xiaopal/kube-informer/pkg/informer"
+)
+
+func TestGRPCAckResult(t *testing.T) {
+	tests := []struct {
+		ack   *grpchandler.EventAck
+		check func(error) bool
+	}{
+		{&grpchandler.EventAck{}, func(err error) bool { return err == nil }},
+		{&grpchandler.EventAck{Error: "failed"}, func(err error) bool { return handlerFailed(err) && !informer.IsPermanentError(err) }},
+		{&grpchandler.EventAck{Error: "failed", Permanent: true}, informer.IsPermanentError},
+		{&grpchandler.EventAck{Error: "failed", Skip: true}, handlerFailed},
+		{&grpchandler.EventAck{Skip: true}, informer.IsSkip},
+		{&grpchandler.EventAck{Skip: true, RequeueAfter: ptypes.DurationProto(time.Minute)}, informer.IsSkip},
+		{&grpchandler.EventAck{RequeueAfter: ptypes.DurationProto(time.Minute)}, requeuedAfter(time.Minute)},
+		{&grpchandler.EventAck{RequeueAfter: &duration.Duration{Seconds: 1, Nanos: -1}}, handlerFailed},
+	}
+	for _, test := range tests {
+		if got := grpcAckResult(test.ack); !test.check(got) {
+			t.Errorf("grpcAckResult(%v) = %v", test.ack, got)
+		}
+	}
+}
@@ -6,12 +6,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/xiaopal/kube-informer/pkg/informer"
@@ -21,43 +24,109 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
-func handleEvent(ctx context.Context, event informer.EventType, obj *unstructured.Unstructured, numRetries int) error {
-	if !handlerEvents[event] {
-		return nil
+// handlerEvent delivers sync events as add, unless they are selected by --event
+func handlerEvent(event informer.EventType) informer.EventType {
+	if event == informer.EventSync && !handlerEvents[informer.EventSync] {
+		return informer.EventAdd
+	}
+	return event
+}
+
+// handlerAccepts checks the event against --event and --when
+func handlerAccepts(event informer.EventType, obj *unstructured.Unstructured) bool {
+	// resync events not selected are objects requeued by handlers
+	if !handlerEvents[event] && event != informer.EventResync {
+		return false
 	}
 	if handlerWhen != nil {
 		if cond, err := handlerWhen(obj); err != nil {
 			if glog.V(2) {
 				logger.Printf("error to execute handler condition: %v", err)
 			}
-			return nil
+			return false
 		} else if cond == "" {
-			return nil
+			return false
 		}
 	}
+	return true
+}
+
+func handleEvent(ctx context.Context, event informer.EventType, obj, oldObj *unstructured.Unstructured, numRetries int) error {
+	event = handlerEvent(event)
+	if !handlerAccepts(event, obj) {
+		return nil
+	}
 	objJSON, err := json.Marshal(obj)
 	if err != nil {
 		return fmt.Errorf("failed to marshal obj: %v", err)
 	}
+	payload, err := newEventPayload(event, obj, oldObj)
+	if err != nil {
+		return err
+	}
 	logger := log.New(os.Stderr, fmt.Sprintf("[%s] ", handlerName), log.Flags())
-	if len(handlerCommand) > 0 {
-		if err := executeHandlerCommand(ctx, event, obj, objJSON, numRetries, logger); err != nil {
-			return err
-		}
-	} else {
+	// handlers skipping the event or requeueing the object do not stop the handlers following
+	var result error
+	if handlerStream != nil {
+		result = handlerStream.handle(ctx, obj, payload, numRetries, logger)
+	} else if len(handlerCommand) > 0 {
+		result = executeHandlerCommand(ctx, event, obj, objJSON, payload, numRetries, logger)
+	} else if grpcHandlerClient == nil {
 		logger.Printf("%s %s.%s: %s/%s", event, obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName())
 	}
-	if len(webhooks) > 0 {
-		if err := executeWebhooks(ctx, event, obj, objJSON, numRetries, logger); err != nil {
-			return err
+	if handlerFailed(result) {
+		return result
+	}
+	if grpcHandlerClient != nil {
+		if result = handlerResult(result, executeGRPCHandler(ctx, event, obj, objJSON, payload, numRetries, logger)); handlerFailed(result) {
+			return result
 		}
 	}
-	return nil
+	if len(webhooks) > 0 {
+		result = handlerResult(result, executeWebhooks(ctx, event, obj, objJSON, payload, numRetries, logger))
+	}
+	return result
+}
+
+// eventPayload carries the old object and the merge patch from it of update events, only with --pass-old-object
+type eventPayload struct {
+	Event     informer.EventType         `json:"event"`
+	Object    *unstructured.Unstructured `json:"object"`
+	OldObject *unstructured.Unstructured `json:"oldObject,omitempty"`
+	Patch     json.RawMessage            `json:"patch,omitempty"`
+	oldJSON   []byte
+}
+
+func newEventPayload(event informer.EventType, obj, oldObj *unstructured.Unstructured) (*eventPayload, error) {
+	payload := &eventPayload{Event: event, Object: obj}
+	if oldObj == nil || !handlerPassOldObject {
+		return payload, nil
+	}
+	oldJSON, err := json.Marshal(oldObj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal old obj: %v", err)
+	}
+	patch, err := json.Marshal(mergePatch(oldObj.Object, obj.Object))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal patch: %v", err)
+	}
+	payload.OldObject, payload.oldJSON, payload.Patch = oldObj, oldJSON, patch
+	return payload, nil
+}
+
+// data returns the payload posted or piped to handlers, the object itself unless --pass-old-object
+func (p *eventPayload) data(objJSON []byte) ([]byte, error) {
+	if !handlerPassOldObject {
+		return objJSON, nil
+	}
+	return json.Marshal(p)
 }
 
-func webhookRequest(webhookBase *url.URL, event informer.EventType, obj *unstructured.Unstructured, objJSON []byte, numRetries int, logger *log.Logger) (*http.Request, error) {
+func webhookRequest(webhookBase *url.URL, event informer.EventType, obj *unstructured.Unstructured, objJSON []byte, payload *eventPayload, numRetries int, logger *log.Logger) (*http.Request, error) {
 	webhook, q := &url.URL{}, webhookBase.Query()
 	q.Set("event", string(event))
+	q.Set("apiVersion", obj.GetAPIVersion())
+	q.Set("kind", obj.GetKind())
 	if numRetries > 0 {
 		q.Set("retries", strconv.Itoa(numRetries))
 	}
@@ -73,42 +142,112 @@ func webhookRequest(webhookBase *url.URL, event informer.EventType, obj *unstruc
 	if !webhookPayload {
 		return http.NewRequest("GET", webhook.String(), nil)
 	}
-	req, err := http.NewRequest("POST", webhook.String(), bytes.NewReader(objJSON))
+	data, err := payload.data(objJSON)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", webhook.String(), bytes.NewReader(data))
 	if err == nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
 	return req, err
 }
 
-func executeWebhooks(ctx context.Context, event informer.EventType, obj *unstructured.Unstructured, objJSON []byte, numRetries int, logger *log.Logger) error {
+// executeWebhooks calls the webhooks in order, actions requeueing the object do not stop the webhooks following
+func executeWebhooks(ctx context.Context, event informer.EventType, obj *unstructured.Unstructured, objJSON []byte, payload *eventPayload, numRetries int, logger *log.Logger) error {
+	var result error
 	for _, webhook := range webhooks {
-		req, err := webhookRequest(webhook, event, obj, objJSON, numRetries, logger)
+		req, err := webhookRequest(webhook, event, obj, objJSON, payload, numRetries, logger)
 		if err != nil {
 			return fmt.Errorf("failed to prepare webhook: %v", err)
 		}
 		reqCtx, endReq := context.WithTimeout(ctx, webhookTimeout)
 		defer endReq()
-		if res, err := http.DefaultClient.Do(req.WithContext(reqCtx)); err != nil {
+		res, err := http.DefaultClient.Do(req.WithContext(reqCtx))
+		if err != nil {
 			return fmt.Errorf("failed to process webhook %s: %v", req.URL.String(), err)
-		} else if res.StatusCode < 200 || res.StatusCode >= 300 {
+		}
+		output, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode >= 300 {
 			return fmt.Errorf("failed to process webhook %s: HTTP %s", req.URL.String(), res.Status)
 		} else if glog.V(2) {
 			logger.Printf("triggerred webhook %s, %s %s.%s: %s/%s", req.URL.String(), event, obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName())
 		}
+		if err != nil {
+			return fmt.Errorf("failed to read webhook %s: %v", req.URL.String(), err)
+		}
+		if result = handlerResult(result, handleOutput(obj, output, logger)); handlerFailed(result) {
+			return result
+		}
 	}
-	return nil
+	return result
 }
-func executeHandlerCommand(ctx context.Context, event informer.EventType, obj *unstructured.Unstructured, objJSON []byte, numRetries int, logger *log.Logger) error {
-	handler := exec.CommandContext(ctx, handlerCommand[0], handlerCommand[1:]...)
-	if err := setupHandler(handler, event, obj, objJSON, numRetries, handlerMaxRetries, logger); err != nil {
+
+func executeHandlerCommand(ctx context.Context, event informer.EventType, obj *unstructured.Unstructured, objJSON []byte, payload *eventPayload, numRetries int, logger *log.Logger) error {
+	handler := exec.Command(handlerCommand[0], handlerCommand[1:]...)
+	if err := setupHandler(handler, event, obj, objJSON, payload, numRetries, handlerMaxRetries, logger); err != nil {
+		return fmt.Errorf("failed to setup handler: %v", err)
+	}
+	output := &bytes.Buffer{}
+	if handlerOutput == handlerOutputJSON {
+		handler.Stdout = output
+	}
+	requeue, err := setupRequeue(handler)
+	if err != nil {
 		return fmt.Errorf("failed to setup handler: %v", err)
 	}
+	defer requeue.remove()
 	subreaper.Pause()
 	defer subreaper.Resume()
-	if err := handler.Run(); err != nil {
+	if err := runHandler(ctx, handler, logger); err != nil {
+		if ok, result := handlerExitResult(err, requeue); ok {
+			return result
+		}
 		return fmt.Errorf("failed to execute handler: %v", err)
 	}
-	return nil
+	return handleOutput(obj, output.Bytes(), logger)
+}
+
+// runHandler runs the handler in its own process group, on --handler-timeout or shutdown the group
+// gets SIGTERM, then SIGKILL after --handler-kill-grace
+func runHandler(ctx context.Context, handler *exec.Cmd, logger *log.Logger) error {
+	if handlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, handlerTimeout)
+		defer cancel()
+	}
+	setupProcessGroup(handler)
+	if err := handler.Start(); err != nil {
+		return err
+	}
+	if err := waitHandler(ctx, handler, logger); err != context.DeadlineExceeded {
+		return err
+	}
+	return fmt.Errorf("timed out after %v", handlerTimeout)
+}
+
+// waitHandler waits for the handler started, on ctx done its process group gets SIGTERM, then SIGKILL after --handler-kill-grace
+func waitHandler(ctx context.Context, handler *exec.Cmd, logger *log.Logger) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	logger.Printf("terminating handler pid %d: %v", handler.Process.Pid, ctx.Err())
+	signalProcessGroup(handler, syscall.SIGTERM)
+	select {
+	case <-done:
+	case <-time.After(handlerKillGrace):
+		logger.Printf("killing handler pid %d", handler.Process.Pid)
+		signalProcessGroup(handler, syscall.SIGKILL)
+		<-done
+	}
+	return ctx.Err()
 }
 
 func formatTimestamp(time *metav1.Time) string {
@@ -119,7 +258,7 @@ func formatTimestamp(time *metav1.Time) string {
 	return ret
 }
 
-func setupHandler(handler *exec.Cmd, event informer.EventType, obj *unstructured.Unstructured, objJSON []byte, numRetries int, maxRetries int, logger *log.Logger) error {
+func setupHandler(handler *exec.Cmd, event informer.EventType, obj *unstructured.Unstructured, objJSON []byte, payload *eventPayload, numRetries int, maxRetries int, logger *log.Logger) error {
 	creationTime := obj.GetCreationTimestamp()
 	handler.Env = append(os.Environ(),
 		fmt.Sprintf("INFORMER_EVENT=%s", event),
@@ -135,12 +274,25 @@ func setupHandler(handler *exec.Cmd, event informer.EventType, obj *unstructured
 	)
 	if handlerPassEnv {
 		handler.Env = append(handler.Env, fmt.Sprintf("INFORMER_OBJECT=%s", string(objJSON)))
+		if handlerPassOldObject && payload.OldObject != nil {
+			handler.Env = append(handler.Env,
+				fmt.Sprintf("INFORMER_OLD_OBJECT=%s", string(payload.oldJSON)),
+				fmt.Sprintf("INFORMER_OBJECT_PATCH=%s", string(payload.Patch)),
+			)
+		}
 	}
 	if handlerPassArgs {
 		handler.Args = append(handler.Args, string(event), string(objJSON))
+		if handlerPassOldObject && payload.OldObject != nil {
+			handler.Args = append(handler.Args, string(payload.oldJSON), string(payload.Patch))
+		}
 	}
 	if handlerPassStdin {
-		handler.Stdin = bytes.NewReader(objJSON)
+		data, err := payload.data(objJSON)
+		if err != nil {
+			return err
+		}
+		handler.Stdin = bytes.NewReader(data)
 	}
 	if err := pipeStderr(handler, logger); err != nil {
 		return fmt.Errorf("failed to pipe stderr: %v", err)
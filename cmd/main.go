@@ -2,24 +2,66 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"os"
 
 	"github.com/xiaopal/kube-informer/pkg/appctx"
 	"github.com/xiaopal/kube-informer/pkg/informer"
 	"github.com/xiaopal/kube-informer/pkg/subreaper"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func runInformer(app appctx.Interface) {
+	if handlerMode == handlerModeStream {
+		handlerStream = newStreamHandler()
+		app.WaitGroup().Add(1)
+		go func() {
+			defer app.WaitGroup().Done()
+			handlerStream.run(app.Context(), log.New(os.Stderr, fmt.Sprintf("[%s] ", handlerName), log.Flags()))
+		}()
+	}
+	if grpcHandlerClient != nil {
+		defer grpcHandlerClient.Close()
+	}
 	i := informer.NewInformer(kubeClient, informer.Opts{
-		Logger:      logger,
-		Handler:     handleEvent,
-		MaxRetries:  handlerMaxRetries,
-		RateLimiter: informer.DefaultRateLimiter(handlerRetriesBaseDelay, handlerRetriesMaxDelay, handlerLimitRate, handlerLimitBursts),
-		Indexers:    httpServerIndexers,
+		Logger:           logger,
+		Handler:          handleEvent,
+		MaxRetries:       handlerMaxRetries,
+		RateLimiter:      informer.DefaultRateLimiter(handlerRetriesBaseDelay, handlerRetriesMaxDelay, handlerLimitRate, handlerLimitBursts),
+		Indexers:         httpServerIndexers,
+		ResolvePeriod:    waitForKindsPeriod,
+		SkipInitialSync:  skipInitialSync || !handlerEvents[informer.EventSync] && !handlerEvents[informer.EventAdd],
+		SkipResync:       !handlerEvents[informer.EventResync],
+		PassOldObject:    handlerPassOldObject,
+		UpdateFilter:     handlerUpdateFilter,
+		Finalizer:        finalizer,
+		Checkpoint:       newCheckpoint(),
+		CheckpointPeriod: checkpointPeriod,
+		Workers:          handlerWorkers,
+		BatchSize:        handlerBatchSize,
+		BatchWindow:      handlerBatchWindow,
+		BatchHandler:     handleBatch,
+		DeadLetters:      newDeadLetterStore(),
+		Debounce:         handlerDebounce,
+		DebounceMaxWait:  handlerDebounceMaxWait,
 	})
 	for _, watch := range watches {
-		err := i.Watch(watch["apiVersion"], watch["kind"], kubeClient.Namespace(), labelSelector, fieldSelector, resyncDuration)
-		if err != nil {
+		if group, version, ok := watchGroup(watch); ok {
+			if err := i.WatchGroup(group, version, watchOpts(watch, schema.GroupVersionKind{})); err != nil {
+				logger.Printf("failed to watch %v: %v", watch, err)
+				return
+			}
+			continue
+		}
+		if resource := watch["resource"]; watch["kind"] == "" {
+			if err := i.WatchResource(resource, watchOpts(watch, schema.GroupVersionKind{})); err != nil {
+				logger.Printf("failed to watch %v: %v", watch, err)
+				return
+			}
+			continue
+		}
+		if err := i.WatchWithOpts(watchOpts(watch, schema.FromAPIVersionAndKind(watch["apiVersion"], watch["kind"]))); err != nil {
 			logger.Printf("failed to watch %v: %v", watch, err)
 			return
 		}
@@ -30,6 +72,9 @@ func runInformer(app appctx.Interface) {
 			DefaultIndex: "/index",
 			IndexPrefix:  "/index/",
 		}
+		if deadLetters != "" {
+			locations.DeadLetters = "/dead-letters"
+		}
 		if proxyAPIServer != "" {
 			locations.APIProxyPrefix, locations.APIProxyAllow = "/api/", proxyAPIServer
 		}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 
@@ -16,6 +18,7 @@ import (
 
 	"time"
 
+	"github.com/xiaopal/kube-informer/pkg/grpchandler"
 	"github.com/xiaopal/kube-informer/pkg/informer"
 	"github.com/xiaopal/kube-informer/pkg/kubeclient"
 	"github.com/xiaopal/kube-informer/pkg/leaderelect"
@@ -24,6 +27,7 @@ import (
 
 	"github.com/Masterminds/sprig"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -31,18 +35,47 @@ var (
 	logger                       *log.Logger
 	watches                      = []map[string]string{}
 	labelSelector, fieldSelector string
+	namespaceSelector            string
+	waitForKinds                 bool
+	waitForKindsPeriod           time.Duration
 	resyncDuration               time.Duration
 	handlerEvents                = map[informer.EventType]bool{}
+	skipInitialSync              bool
 	handlerCommand               []string
 	webhooks                     []*url.URL
 	webhookTimeout               = 30 * time.Second
 	webhookPayload               = true
 	webhookParams                = map[string]func(obj *unstructured.Unstructured) (string, error){}
 	handlerWhen                  func(obj *unstructured.Unstructured) (string, error)
+	handlerUpdateFilter          func(oldObj, newObj *unstructured.Unstructured) bool
+	ignoreFields                 []string
+	finalizer                    string
+	checkpoint                   string
+	deadLetters                  string
+	handlerWorkers               int
+	exitCodeSkip                 int
+	exitCodePermanent            int
+	exitCodeRequeue              int
+	requeueAfterDefault          time.Duration
+	handlerTimeout               time.Duration
+	handlerKillGrace             time.Duration
+	handlerOutput                string
+	handlerMode                  string
+	grpcHandler                  string
+	grpcHandlerClient            *grpchandler.Client
+	handlerBatchSize             int
+	handlerBatchWindow           time.Duration
+	handlerDebounce              time.Duration
+	handlerDebounceMaxWait       time.Duration
+	checkpointPeriod             time.Duration
+	transformDrop, transformKeep []string
+	transformTemplate            func(obj *unstructured.Unstructured) (string, error)
+	namedTransforms              = map[string]func(obj *unstructured.Unstructured) (string, error){}
 	handlerName                  string
 	handlerPassStdin             bool
 	handlerPassEnv               bool
 	handlerPassArgs              bool
+	handlerPassOldObject         bool
 	handlerMaxRetries            int
 	handlerLimitRate             float64
 	handlerLimitBursts           int
@@ -56,16 +89,290 @@ var (
 	leaderHelper                 leaderelect.Helper
 )
 
+// watchKeys are the optional keys of --watch, a missing key falls back to the global flag
+var watchKeys = []struct{ key, usage string }{
+	{"namespace", "namespaces instead of --namespace, ';' as separator"},
+	{"namespaceSelector", "label query of namespaces instead of --namespace-selector, ';' as separator"},
+	{"selector", "label query instead of --selector, ';' as separator"},
+	{"fieldSelector", "field query instead of --field-selector, ';' as separator"},
+	{"resync", "resync period instead of --resync"},
+	{"wait", "true|false, wait for the kind or resource to be served instead of --wait-kinds"},
+	{"metadataOnly", "true|false, cache and handle metadata of objects only"},
+	{"fetchObject", "true|false, get the whole object before handling, eg. with metadataOnly=true"},
+	{"drop", "json paths to drop instead of --drop, ';' as separator"},
+	{"keep", "json paths to keep instead of --keep, ';' as separator"},
+	{"transform", "name of the --named-transform instead of --transform, empty to disable"},
+}
+
+func watchUsage() string {
+	usage := "watch resources, eg. `apiVersion=v1,kind=ConfigMap`, apiVersion=example.com/v1,kind=*, group=example.com, configmaps, deployments.apps, deploy, pods/v1, all, ':' to separate watches, optional keys per watch:"
+	for _, key := range watchKeys {
+		usage += fmt.Sprintf("\n  %-19s %s", key.key+"=", key.usage)
+	}
+	return usage
+}
+
 func parseWatch(watch string) map[string]string {
 	opts := map[string]string{"apiVersion": "v1"}
 	for _, s := range strings.Split(watch, ",") {
 		if opt := strings.SplitN(s, "=", 2); len(opt) == 2 {
 			opts[strings.TrimSpace(opt[0])] = strings.TrimSpace(opt[1])
+		} else if resource := strings.TrimSpace(s); resource != "" {
+			opts["resource"] = resource
 		}
 	}
 	return opts
 }
 
+func watchOpt(watch map[string]string, key string, defaultValue string) string {
+	if val, ok := watch[key]; ok {
+		return val
+	}
+	return defaultValue
+}
+
+func watchBool(watch map[string]string, key string, defaultValue bool) bool {
+	if val, err := strconv.ParseBool(watchOpt(watch, key, strconv.FormatBool(defaultValue))); err == nil {
+		return val
+	}
+	return defaultValue
+}
+
+func watchNamespaces(watch map[string]string) []string {
+	if namespace, ok := watch["namespace"]; ok {
+		namespaces := []string{}
+		for _, ns := range strings.Split(namespace, ";") {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				namespaces = append(namespaces, ns)
+			}
+		}
+		return namespaces
+	}
+	return kubeClient.Namespaces()
+}
+
+func watchNamespaceSelector(watch map[string]string) string {
+	if _, ok := watch["namespace"]; ok {
+		return watch["namespaceSelector"]
+	}
+	return watchOpt(watch, "namespaceSelector", namespaceSelector)
+}
+
+func watchGroup(watch map[string]string) (string, string, bool) {
+	if group := watch["group"]; group != "" {
+		return group, watch["version"], true
+	}
+	if watch["kind"] == "*" {
+		gv, _ := schema.ParseGroupVersion(watch["apiVersion"])
+		return gv.Group, gv.Version, true
+	}
+	return "", "", false
+}
+
+func watchOpts(watch map[string]string, gvk schema.GroupVersionKind) informer.WatchOpts {
+	apiVersion, kind := gvk.ToAPIVersionAndKind()
+	resync, _ := time.ParseDuration(watchOpt(watch, "resync", resyncDuration.String()))
+	return informer.WatchOpts{
+		APIVersion:        apiVersion,
+		Kind:              kind,
+		Namespaces:        watchNamespaces(watch),
+		LabelSelector:     watchOpt(watch, "selector", labelSelector),
+		FieldSelector:     watchOpt(watch, "fieldSelector", fieldSelector),
+		Resync:            resync,
+		NamespaceSelector: watchNamespaceSelector(watch),
+		WaitForKind:       watchBool(watch, "wait", waitForKinds),
+		MetadataOnly:      watchBool(watch, "metadataOnly", false),
+		FetchObject:       watchBool(watch, "fetchObject", false),
+		Transform:         watchTransform(watch),
+	}
+}
+
+// identityPaths are always kept by transforms
+var identityPaths = []string{"apiVersion", "kind", "metadata.name", "metadata.namespace", "metadata.uid", "metadata.resourceVersion", "metadata.deletionTimestamp", "metadata.finalizers"}
+
+func watchTransformPaths(watch map[string]string) ([]objectPath, []objectPath, error) {
+	drop, keep := transformDrop, transformKeep
+	if val, ok := watch["drop"]; ok {
+		drop = strings.Split(val, ";")
+	}
+	if val, ok := watch["keep"]; ok {
+		keep = strings.Split(val, ";")
+	}
+	dropPaths, err := parseObjectPaths(drop)
+	if err != nil {
+		return nil, nil, err
+	}
+	keepPaths, err := parseObjectPaths(keep)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keepPaths) > 0 {
+		identity, _ := parseObjectPaths(identityPaths)
+		keepPaths = append(keepPaths, identity...)
+	}
+	return dropPaths, keepPaths, nil
+}
+
+// watchTransformTemplate returns the --named-transform selected by `transform=` of the watch, or --transform,
+// an empty `transform=` disables --transform
+func watchTransformTemplate(watch map[string]string) (func(*unstructured.Unstructured) (string, error), error) {
+	name, ok := watch["transform"]
+	if !ok {
+		return transformTemplate, nil
+	}
+	if name == "" {
+		return nil, nil
+	}
+	if tmpl, ok := namedTransforms[name]; ok {
+		return tmpl, nil
+	}
+	return nil, fmt.Errorf("unknown transform %s", name)
+}
+
+func watchTransform(watch map[string]string) func(*unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	dropPaths, keepPaths, _ := watchTransformPaths(watch)
+	transformTemplate, _ := watchTransformTemplate(watch)
+	if len(dropPaths) == 0 && len(keepPaths) == 0 && transformTemplate == nil {
+		return nil
+	}
+	return func(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+		for _, path := range dropPaths {
+			removePath(obj.Object, path)
+		}
+		if len(keepPaths) > 0 {
+			retainPaths(obj.Object, keepPaths)
+		}
+		if transformTemplate == nil {
+			return obj, nil
+		}
+		transformed, err := transformTemplate(obj)
+		if err != nil {
+			return nil, err
+		}
+		ret := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		if err := json.Unmarshal([]byte(transformed), &ret.Object); err != nil {
+			return nil, fmt.Errorf("failed to parse transformed json: %v", err)
+		}
+		ret.SetAPIVersion(obj.GetAPIVersion())
+		ret.SetKind(obj.GetKind())
+		ret.SetName(obj.GetName())
+		ret.SetNamespace(obj.GetNamespace())
+		ret.SetUID(obj.GetUID())
+		ret.SetResourceVersion(obj.GetResourceVersion())
+		ret.SetDeletionTimestamp(obj.GetDeletionTimestamp())
+		ret.SetFinalizers(obj.GetFinalizers())
+		return ret, nil
+	}
+}
+
+// ignoreFieldsPreset ignores everything except the spec, labels and annotations
+const ignoreFieldsPreset = "@spec"
+
+// ignoredAlways are never compared once --ignore-fields is set, they change on every write
+var ignoredAlways = []string{"metadata.resourceVersion", "metadata.managedFields"}
+
+// ignoreFieldsFilter drops updates of objects that are equal after removing the paths
+func ignoreFieldsFilter(fields []string) (func(oldObj, newObj *unstructured.Unstructured) bool, error) {
+	preset, paths := false, []string{}
+	for _, field := range fields {
+		if field == ignoreFieldsPreset {
+			preset = true
+		} else {
+			paths = append(paths, field)
+		}
+	}
+	dropPaths, err := parseObjectPaths(append(paths, ignoredAlways...))
+	if err != nil {
+		return nil, err
+	}
+	keepPaths, _ := parseObjectPaths([]string{"spec", "metadata.labels", "metadata.annotations"})
+	stripped := func(obj *unstructured.Unstructured) map[string]interface{} {
+		ret := obj.DeepCopy().Object
+		if preset {
+			retainPaths(ret, keepPaths)
+		}
+		for _, path := range dropPaths {
+			removePath(ret, path)
+		}
+		return ret
+	}
+	return func(oldObj, newObj *unstructured.Unstructured) bool {
+		return !reflect.DeepEqual(stripped(oldObj), stripped(newObj))
+	}, nil
+}
+
+// onChangeFilter drops updates rendering the same string for the old and new objects
+func onChangeFilter(onChange func(*unstructured.Unstructured) (string, error)) func(oldObj, newObj *unstructured.Unstructured) bool {
+	return func(oldObj, newObj *unstructured.Unstructured) bool {
+		oldVal, oldErr := onChange(oldObj)
+		newVal, newErr := onChange(newObj)
+		return oldVal != newVal || (oldErr == nil) != (newErr == nil)
+	}
+}
+
+// allFilters passes updates passing all of the filters
+func allFilters(filters ...func(oldObj, newObj *unstructured.Unstructured) bool) func(oldObj, newObj *unstructured.Unstructured) bool {
+	return func(oldObj, newObj *unstructured.Unstructured) bool {
+		for _, filter := range filters {
+			if !filter(oldObj, newObj) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func newCheckpoint() informer.Checkpoint {
+	if checkpoint == "" {
+		return nil
+	}
+	if name := strings.TrimPrefix(checkpoint, "configmaps/"); name != checkpoint {
+		namespace := kubeClient.DefaultNamespace()
+		if index := strings.Index(name, "/"); index > 0 {
+			namespace, name = name[:index], name[index+1:]
+		}
+		return informer.NewConfigMapCheckpoint(kubeClient, namespace, name)
+	}
+	return informer.NewFileCheckpoint(checkpoint)
+}
+
+func newDeadLetterStore() informer.DeadLetterStore {
+	if deadLetters == "" {
+		return nil
+	}
+	return informer.NewFileDeadLetterStore(deadLetters)
+}
+
+func checkWatch(watch map[string]string) error {
+	if watch["kind"] == "" && watch["resource"] == "" && watch["group"] == "" {
+		return fmt.Errorf("kind, resource or group required")
+	}
+	if _, err := schema.ParseGroupVersion(watch["apiVersion"]); err != nil {
+		return fmt.Errorf("invalid apiVersion %s: %v", watch["apiVersion"], err)
+	}
+	for _, key := range []string{"selector", "fieldSelector", "namespaceSelector"} {
+		if selector, ok := watch[key]; ok {
+			watch[key] = strings.Replace(selector, ";", ",", -1)
+		}
+	}
+	for _, key := range []string{"wait", "metadataOnly", "fetchObject"} {
+		if val, ok := watch[key]; ok {
+			if _, err := strconv.ParseBool(val); err != nil {
+				return fmt.Errorf("invalid %s %s: %v", key, val, err)
+			}
+		}
+	}
+	if _, _, err := watchTransformPaths(watch); err != nil {
+		return fmt.Errorf("invalid transform: %v", err)
+	}
+	if resync, ok := watch["resync"]; ok {
+		if _, err := time.ParseDuration(resync); err != nil {
+			return fmt.Errorf("invalid resync %s: %v", resync, err)
+		}
+	}
+	return nil
+}
+
 func envToInt(key string, d int) int {
 	if v := os.Getenv(key); v != "" {
 		if ret, err := strconv.Atoi(v); err == nil {
@@ -121,11 +428,12 @@ func bindOptions(mainProc func()) *cobra.Command {
 	//glog.CopyStandardLogTo("INFO")
 	logger = log.New(os.Stderr, "[kube-informer] ", log.Flags())
 
-	argWatches, argEvents, argWebhooks, argWebhookParams, argIndexes, argWhen := []string{},
+	argWatches, argEvents, argWebhooks, argWebhookParams, argIndexes, argWhen, argOnChange, argTransform := []string{},
 		[]string{string(informer.EventAdd), string(informer.EventUpdate), string(informer.EventDelete)},
 		[]string{}, map[string]string{},
 		map[string]string{},
-		""
+		"", "", ""
+	argNamedTransforms := map[string]string{}
 	initOpts, checkOpts := func(cmd *cobra.Command) {
 		flags := cmd.Flags()
 		flags.AddGoFlagSet(flag.CommandLine)
@@ -145,13 +453,26 @@ func bindOptions(mainProc func()) *cobra.Command {
 		if envEvents := os.Getenv("INFORMER_OPTS_EVENT"); envEvents != "" {
 			argEvents = strings.Split(envEvents, ",")
 		}
-		flags.StringArrayVarP(&argWatches, "watch", "w", argWatches, "watch resources, eg. `apiVersion=v1,kind=ConfigMap`")
+		flags.StringArrayVarP(&argWatches, "watch", "w", argWatches, watchUsage())
 		flags.StringVarP(&labelSelector, "selector", "l", os.Getenv("INFORMER_OPTS_SELECTOR"), "selector (label query) to filter on")
+		flags.StringVar(&namespaceSelector, "namespace-selector", os.Getenv("INFORMER_OPTS_NAMESPACE_SELECTOR"), "watch namespaces matching the selector (label query), instead of --namespace")
 		flags.StringVar(&fieldSelector, "field-selector", os.Getenv("INFORMER_OPTS_FIELD_SELECTOR"), "selector (field query) to filter on")
+		flags.BoolVar(&waitForKinds, "wait-kinds", os.Getenv("INFORMER_OPTS_WAIT_KINDS") != "", "wait for kinds not installed yet (eg. CRDs) instead of exiting")
+		flags.DurationVar(&waitForKindsPeriod, "wait-kinds-period", envToDuration("INFORMER_OPTS_WAIT_KINDS_PERIOD", 10*time.Second), "interval to check the kinds waiting for")
 		flags.DurationVar(&resyncDuration, "resync", envToDuration("INFORMER_OPTS_RESYNC", 0), "resync period")
-		flags.StringSliceVarP(&argEvents, "event", "e", argEvents, "handle events")
+		flags.StringArrayVar(&transformDrop, "drop", transformDrop, "drop json paths from objects before caching, eg. `metadata.managedFields`, `metadata.annotations[kubectl.kubernetes.io/last-applied-configuration]`")
+		flags.StringArrayVar(&transformKeep, "keep", transformKeep, "keep only json paths (and apiVersion, kind, metadata.name ...) of objects before caching, eg. `spec`, `metadata.labels`")
+		flags.StringVar(&argTransform, "transform", argTransform, "transform objects before caching, define as go template(with sprig funcs) rendering json")
+		flags.StringToStringVar(&argNamedTransforms, "named-transform", argNamedTransforms, "transforms selected per watch by transform=<name>, define as go template(with sprig funcs) rendering json")
+		flags.StringSliceVarP(&argEvents, "event", "e", argEvents, "handle events: add, update, delete, sync(objects of the initial list, handled as add unless selected), resync(unchanged objects on --resync, objects requeued by handlers are handled as resync anyway)")
+		flags.StringVar(&finalizer, "finalizer", os.Getenv("INFORMER_OPTS_FINALIZER"), "add the finalizer to watched objects, handle deletions as delete events before removing it, eg. `example.com/cleanup`")
+		flags.StringVar(&checkpoint, "checkpoint", os.Getenv("INFORMER_OPTS_CHECKPOINT"), "skip objects handled before restarts, and handle objects deleted meanwhile: <json file path>|configmaps/[<namespace>/]<name>")
+		flags.DurationVar(&checkpointPeriod, "checkpoint-period", envToDuration("INFORMER_OPTS_CHECKPOINT_PERIOD", 5*time.Second), "checkpoint save period")
+		flags.BoolVar(&skipInitialSync, "skip-initial-sync", os.Getenv("INFORMER_OPTS_SKIP_INITIAL_SYNC") != "", "skip sync events of the initial list")
 		flags.StringVar(&handlerName, "name", os.Getenv("INFORMER_OPTS_NAME"), "handler name")
 		flags.StringVar(&argWhen, "when", argWhen, "handler condition, define as go template(with sprig funcs)")
+		flags.StringVar(&argOnChange, "on-change", argOnChange, "handle updates only if the value changes, define as go template(with sprig funcs), eg. `{{.spec.replicas}}`")
+		flags.StringSliceVar(&ignoreFields, "ignore-fields", ignoreFields, "ignore updates changing only these paths, eg. `status.conditions[].lastHeartbeatTime`, `@spec` to ignore all but spec, labels and annotations")
 		flags.StringArrayVar(&argWebhooks, "webhook", argWebhooks, "define handler webhook")
 		flags.DurationVar(&webhookTimeout, "webhook-timeout", webhookTimeout, "handler webhook timeout")
 		flags.BoolVar(&webhookPayload, "webhook-payload", webhookPayload, "post object data to handler webhook")
@@ -159,6 +480,22 @@ func bindOptions(mainProc func()) *cobra.Command {
 		flags.BoolVar(&handlerPassStdin, "pass-stdin", os.Getenv("INFORMER_OPTS_PASS_STDIN") != "", "pass obj json to handler stdin")
 		flags.BoolVar(&handlerPassEnv, "pass-env", os.Getenv("INFORMER_OPTS_PASS_ENV") != "", "pass obj json to handler env INFORMER_OBJECT")
 		flags.BoolVar(&handlerPassArgs, "pass-args", os.Getenv("INFORMER_OPTS_PASS_ARGS") != "", "pass event and obj json to handler arg")
+		flags.BoolVar(&handlerPassOldObject, "pass-old-object", os.Getenv("INFORMER_OPTS_PASS_OLD_OBJECT") != "", "pass old obj json and merge patch of updates, as extra handler args, as $INFORMER_OLD_OBJECT and $INFORMER_OBJECT_PATCH with --pass-env, as {\"event\",\"object\",\"oldObject\",\"patch\"} to handler stdin and webhook, and to stream and grpc handlers")
+		flags.IntVar(&handlerWorkers, "workers", envToInt("INFORMER_OPTS_WORKERS", 1), "handle events of different objects in parallel")
+		flags.DurationVar(&handlerDebounce, "debounce", envToDuration("INFORMER_OPTS_DEBOUNCE", 0), "handle objects once with the final state after no event arrived for the window")
+		flags.DurationVar(&handlerDebounceMaxWait, "debounce-max-wait", envToDuration("INFORMER_OPTS_DEBOUNCE_MAX_WAIT", 0), "handle objects changing all the time after the max wait since the first event, 10 times --debounce by default")
+		flags.IntVar(&handlerBatchSize, "batch-size", envToInt("INFORMER_OPTS_BATCH_SIZE", 0), "handle up to this many events at once, as json array `[{\"event\",\"object\",\"oldObject\",\"patch\",\"retries\"}]` to handler stdin and webhook, the handler may write indexes of failed items to the file $INFORMER_BATCH_FAILED, webhooks may respond `{\"failed\":[...]}`")
+		flags.DurationVar(&handlerBatchWindow, "batch-window", envToDuration("INFORMER_OPTS_BATCH_WINDOW", time.Second), "max time to gather a batch")
+		flags.StringVar(&deadLetters, "dead-letters", os.Getenv("INFORMER_OPTS_DEAD_LETTERS"), "store events given up after max retries to the json lines file, listed and requeued on http server `/dead-letters`")
+		flags.DurationVar(&handlerTimeout, "handler-timeout", envToDuration("INFORMER_OPTS_HANDLER_TIMEOUT", 0), "handler timeout, timed out handlers are retried")
+		flags.DurationVar(&handlerKillGrace, "handler-kill-grace", envToDuration("INFORMER_OPTS_HANDLER_KILL_GRACE", 10*time.Second), "grace period between SIGTERM and SIGKILL of the handler process group on timeout or shutdown")
+		flags.StringVar(&handlerMode, "handler-mode", os.Getenv("INFORMER_OPTS_HANDLER_MODE"), "handler mode: stream to start the handler once, write events to its stdin as json lines {\"id\",\"event\",\"object\",\"oldObject\",\"patch\",\"retries\"} and read acks {\"id\",\"error\",\"requeueAfter\",...} from its stdout, it is restarted with backoff if exited")
+		flags.StringVar(&grpcHandler, "grpc-handler", os.Getenv("INFORMER_OPTS_GRPC_HANDLER"), "call HandleEvent of the gRPC handler service (pkg/grpchandler/handler.proto) for each event, eg. `unix:///run/handler.sock`")
+		flags.StringVar(&handlerOutput, "handler-output", os.Getenv("INFORMER_OPTS_HANDLER_OUTPUT"), "handler output: json to carry out actions returned on stdout or in webhook responses, eg. {\"requeueAfter\":30,\"patch\":{...},\"annotations\":{...},\"event\":{\"type\":\"Normal\",\"reason\":...,\"message\":...}}")
+		flags.IntVar(&exitCodeSkip, "exit-code-skip", envToInt("INFORMER_OPTS_EXIT_CODE_SKIP", 0), "handler exit code to skip the event, not recorded to the checkpoint")
+		flags.IntVar(&exitCodePermanent, "exit-code-permanent", envToInt("INFORMER_OPTS_EXIT_CODE_PERMANENT", 0), "handler exit code of permanent failures, not retried")
+		flags.IntVar(&exitCodeRequeue, "exit-code-requeue", envToInt("INFORMER_OPTS_EXIT_CODE_REQUEUE", 0), "handler exit code to succeed and handle the object again as resync after the delay written to the file $INFORMER_REQUEUE_AFTER_FILE, in seconds or as duration")
+		flags.DurationVar(&requeueAfterDefault, "requeue-after", envToDuration("INFORMER_OPTS_REQUEUE_AFTER", time.Minute), "requeue delay if the handler exits with --exit-code-requeue but writes no delay")
 		flags.IntVar(&handlerMaxRetries, "max-retries", envToInt("INFORMER_OPTS_MAX_RETRIES", 15), "handler max retries, -1 for unlimited")
 		flags.DurationVar(&handlerRetriesBaseDelay, "retries-base-delay", envToDuration("INFORMER_OPTS_RETRIES_BASE_DELAY", 5*time.Millisecond), "handler retries: base delay")
 		flags.DurationVar(&handlerRetriesMaxDelay, "retries-max-delay", envToDuration("INFORMER_OPTS_RETRIES_MAX_DELAY", 1000*time.Second), "handler retries: max delay")
@@ -183,7 +520,11 @@ func bindOptions(mainProc func()) *cobra.Command {
 		for _, line := range argWatches {
 			for _, watch := range strings.Split(line, ":") {
 				if strings.TrimSpace(watch) != "" {
-					watches = append(watches, parseWatch(watch))
+					opts := parseWatch(watch)
+					if err := checkWatch(opts); err != nil {
+						return fmt.Errorf("error to parse watch %s: %v", watch, err)
+					}
+					watches = append(watches, opts)
 				}
 			}
 		}
@@ -199,12 +540,65 @@ func bindOptions(mainProc func()) *cobra.Command {
 			return fmt.Errorf("invalid --template-delims")
 		}
 
+		if handlerOutput != "" && handlerOutput != handlerOutputJSON {
+			return fmt.Errorf("invalid --handler-output %s", handlerOutput)
+		}
+		if handlerMode != "" && handlerMode != handlerModeStream {
+			return fmt.Errorf("invalid --handler-mode %s", handlerMode)
+		}
+		if handlerMode == handlerModeStream && (len(handlerCommand) == 0 || handlerBatchSize > 0) {
+			return fmt.Errorf("--handler-mode=stream requires a handler command, and no --batch-size")
+		}
+		if grpcHandler != "" {
+			if handlerBatchSize > 0 {
+				return fmt.Errorf("--grpc-handler conflicts with --batch-size")
+			}
+			if grpcHandlerClient, err = grpchandler.NewClient(grpcHandler); err != nil {
+				return fmt.Errorf("invalid --grpc-handler %s: %v", grpcHandler, err)
+			}
+		}
+
+		if argTransform != "" {
+			if transformTemplate, err = objectTemplate("transform", argTransform); err != nil {
+				return fmt.Errorf("error to parse transform %s: %v", argTransform, err)
+			}
+		}
+		for name, template := range argNamedTransforms {
+			if namedTransforms[name], err = objectTemplate(name, template); err != nil {
+				return fmt.Errorf("error to parse transform %s: %v", name, err)
+			}
+		}
+		for _, watch := range watches {
+			if _, err := watchTransformTemplate(watch); err != nil {
+				return fmt.Errorf("error to parse watch %v: %v", watch, err)
+			}
+		}
+
 		if argWhen != "" {
 			if handlerWhen, err = objectTemplate("when", argWhen); err != nil {
 				return fmt.Errorf("error to parse handler condition %s: %v", argWhen, err)
 			}
 		}
 
+		updateFilters := []func(oldObj, newObj *unstructured.Unstructured) bool{}
+		if len(ignoreFields) > 0 {
+			filter, err := ignoreFieldsFilter(ignoreFields)
+			if err != nil {
+				return fmt.Errorf("error to parse ignore fields %v: %v", ignoreFields, err)
+			}
+			updateFilters = append(updateFilters, filter)
+		}
+		if argOnChange != "" {
+			onChange, err := objectTemplate("on-change", argOnChange)
+			if err != nil {
+				return fmt.Errorf("error to parse on change %s: %v", argOnChange, err)
+			}
+			updateFilters = append(updateFilters, onChangeFilter(onChange))
+		}
+		if len(updateFilters) > 0 {
+			handlerUpdateFilter = allFilters(updateFilters...)
+		}
+
 		for _, webhook := range argWebhooks {
 			webhookURL, err := url.Parse(webhook)
 			if err != nil {
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// objectPath is a parsed json path, eg. `metadata.annotations[kubectl.kubernetes.io/last-applied-configuration]`,
+// `[key]` for keys containing dots, `[]` for each element of arrays, eg. `status.conditions[].lastHeartbeatTime`
+type objectPath []string
+
+const pathEach = "[]"
+
+func parseObjectPath(path string) (objectPath, error) {
+	ret := objectPath{}
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in path %s", path)
+			}
+			if key := path[i+1 : i+end]; key != "" {
+				ret = append(ret, key)
+			} else {
+				ret = append(ret, pathEach)
+			}
+			i += end + 1
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			ret = append(ret, path[i:i+end])
+			i += end
+		}
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return ret, nil
+}
+
+func parseObjectPaths(paths []string) ([]objectPath, error) {
+	ret := []objectPath{}
+	for _, path := range paths {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		p, err := parseObjectPath(path)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+// removePath removes the path from v in place
+func removePath(v interface{}, path objectPath) {
+	if len(path) == 0 {
+		return
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(val, path[0])
+		} else if child, ok := val[path[0]]; ok {
+			removePath(child, path[1:])
+		}
+	case []interface{}:
+		if path[0] == pathEach {
+			for _, item := range val {
+				removePath(item, path[1:])
+			}
+		}
+	}
+}
+
+// retainPaths removes everything not on the paths from v in place
+func retainPaths(v interface{}, paths []objectPath) {
+	for _, path := range paths {
+		if len(path) == 0 {
+			return
+		}
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			sub := []objectPath{}
+			for _, path := range paths {
+				if path[0] == key {
+					sub = append(sub, path[1:])
+				}
+			}
+			if len(sub) == 0 {
+				delete(val, key)
+			} else {
+				retainPaths(child, sub)
+			}
+		}
+	case []interface{}:
+		sub := []objectPath{}
+		for _, path := range paths {
+			if path[0] == pathEach {
+				sub = append(sub, path[1:])
+			}
+		}
+		if len(sub) > 0 {
+			for _, item := range val {
+				retainPaths(item, sub)
+			}
+		}
+	}
+}
+
+// mergePatch returns the JSON merge patch (RFC 7386) turning oldObj into newObj
+func mergePatch(oldObj, newObj map[string]interface{}) map[string]interface{} {
+	ret := map[string]interface{}{}
+	for key := range oldObj {
+		if _, ok := newObj[key]; !ok {
+			ret[key] = nil
+		}
+	}
+	for key, newVal := range newObj {
+		oldVal, ok := oldObj[key]
+		if ok && reflect.DeepEqual(oldVal, newVal) {
+			continue
+		}
+		oldMap, oldIsMap := oldVal.(map[string]interface{})
+		newMap, newIsMap := newVal.(map[string]interface{})
+		if oldIsMap && newIsMap {
+			ret[key] = mergePatch(oldMap, newMap)
+		} else {
+			ret[key] = newVal
+		}
+	}
+	return ret
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func parseJSON(t *testing.T, data string) map[string]interface{} {
+	ret := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(data), &ret); err != nil {
+		t.Fatalf("failed to parse %s: %v", data, err)
+	}
+	return ret
+}
+
+func TestParseObjectPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want objectPath
+	}{
+		{"spec", objectPath{"spec"}},
+		{"metadata.managedFields", objectPath{"metadata", "managedFields"}},
+		{"metadata.annotations[kubectl.kubernetes.io/last-applied-configuration]", objectPath{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"}},
+		{"status.conditions[].lastHeartbeatTime", objectPath{"status", "conditions", pathEach, "lastHeartbeatTime"}},
+		{"[a.b][]", objectPath{"a.b", pathEach}},
+		{".spec.", objectPath{"spec"}},
+		{"", nil},
+		{".", nil},
+		{"metadata.labels[app", nil},
+	}
+	for _, test := range tests {
+		got, err := parseObjectPath(test.path)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("parseObjectPath(%q) = %q, want error", test.path, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseObjectPath(%q) failed: %v", test.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseObjectPath(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestParseObjectPaths(t *testing.T) {
+	got, err := parseObjectPaths([]string{" spec ", "", "metadata.labels"})
+	if err != nil {
+		t.Fatalf("parseObjectPaths failed: %v", err)
+	}
+	if want := []objectPath{{"spec"}, {"metadata", "labels"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseObjectPaths = %q, want %q", got, want)
+	}
+	if _, err := parseObjectPaths([]string{"spec", "status[x"}); err == nil {
+		t.Errorf("parseObjectPaths with an invalid path succeeded")
+	}
+}
+
+const pathsObject = `{
+	"metadata": {"name": "a", "annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}", "x": "y"}, "managedFields": [{}]},
+	"spec": {"replicas": 1},
+	"status": {"conditions": [{"type": "Ready", "lastHeartbeatTime": "t1"}, {"type": "Synced", "lastHeartbeatTime": "t2"}]}
+}`
+
+func TestRemovePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"metadata.managedFields", `{
+			"metadata": {"name": "a", "annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}", "x": "y"}},
+			"spec": {"replicas": 1},
+			"status": {"conditions": [{"type": "Ready", "lastHeartbeatTime": "t1"}, {"type": "Synced", "lastHeartbeatTime": "t2"}]}
+		}`},
+		{"metadata.annotations[kubectl.kubernetes.io/last-applied-configuration]", `{
+			"metadata": {"name": "a", "annotations": {"x": "y"}, "managedFields": [{}]},
+			"spec": {"replicas": 1},
+			"status": {"conditions": [{"type": "Ready", "lastHeartbeatTime": "t1"}, {"type": "Synced", "lastHeartbeatTime": "t2"}]}
+		}`},
+		{"status.conditions[].lastHeartbeatTime", `{
+			"metadata": {"name": "a", "annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}", "x": "y"}, "managedFields": [{}]},
+			"spec": {"replicas": 1},
+			"status": {"conditions": [{"type": "Ready"}, {"type": "Synced"}]}
+		}`},
+		{"spec.replicas.value", pathsObject},
+		{"spec[].replicas", pathsObject},
+		{"missing.path", pathsObject},
+	}
+	for _, test := range tests {
+		path, err := parseObjectPath(test.path)
+		if err != nil {
+			t.Fatalf("parseObjectPath(%q) failed: %v", test.path, err)
+		}
+		obj := parseJSON(t, pathsObject)
+		removePath(obj, path)
+		if want := parseJSON(t, test.want); !reflect.DeepEqual(obj, want) {
+			t.Errorf("removePath(%q) = %v, want %v", test.path, obj, want)
+		}
+	}
+}
+
+func TestRetainPaths(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"spec"}, `{"spec": {"replicas": 1}}`},
+		{[]string{"metadata.name", "spec.replicas"}, `{"metadata": {"name": "a"}, "spec": {"replicas": 1}}`},
+		{[]string{"metadata.annotations[x]"}, `{"metadata": {"annotations": {"x": "y"}}}`},
+		{[]string{"status.conditions[].type"}, `{"status": {"conditions": [{"type": "Ready"}, {"type": "Synced"}]}}`},
+		{[]string{"status.conditions"}, `{"status": {"conditions": [{"type": "Ready", "lastHeartbeatTime": "t1"}, {"type": "Synced", "lastHeartbeatTime": "t2"}]}}`},
+		{[]string{"spec.replicas.value"}, `{"spec": {"replicas": 1}}`},
+		{[]string{"missing"}, `{}`},
+	}
+	for _, test := range tests {
+		paths, err := parseObjectPaths(test.paths)
+		if err != nil {
+			t.Fatalf("parseObjectPaths(%q) failed: %v", test.paths, err)
+		}
+		obj := parseJSON(t, pathsObject)
+		retainPaths(obj, paths)
+		if want := parseJSON(t, test.want); !reflect.DeepEqual(obj, want) {
+			t.Errorf("retainPaths(%q) = %v, want %v", test.paths, obj, want)
+		}
+	}
+}
+
+func TestWatchTransformTemplate(t *testing.T) {
+	defer func(global func(*unstructured.Unstructured) (string, error), named map[string]func(*unstructured.Unstructured) (string, error)) {
+		transformTemplate, namedTransforms = global, named
+	}(transformTemplate, namedTransforms)
+	var err error
+	if transformTemplate, err = objectTemplate("transform", `{"global": true}`); err != nil {
+		t.Fatal(err)
+	}
+	namedTransforms = map[string]func(*unstructured.Unstructured) (string, error){}
+	if namedTransforms["spec"], err = objectTemplate("spec", `{"spec": {{toJson .spec}}}`); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		watch map[string]string
+		want  string
+	}{
+		{map[string]string{}, `{"global": true}`},
+		{map[string]string{"transform": "spec"}, `{"spec": {"replicas":1}}`},
+		{map[string]string{"transform": ""}, ""},
+		{map[string]string{"transform": "missing"}, "error"},
+	}
+	obj := &unstructured.Unstructured{Object: parseJSON(t, pathsObject)}
+	for _, test := range tests {
+		tmpl, err := watchTransformTemplate(test.watch)
+		switch {
+		case test.want == "error":
+			if err == nil {
+				t.Errorf("watchTransformTemplate(%v) succeeded, want error", test.watch)
+			}
+		case err != nil:
+			t.Errorf("watchTransformTemplate(%v) failed: %v", test.watch, err)
+		case test.want == "":
+			if tmpl != nil {
+				t.Errorf("watchTransformTemplate(%v) returned a template, want none", test.watch)
+			}
+		default:
+			if got, err := tmpl(obj); err != nil || got != test.want {
+				t.Errorf("watchTransformTemplate(%v) rendered %s, %v, want %s", test.watch, got, err, test.want)
+			}
+		}
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		oldObj, newObj, want string
+	}{
+		{`{"a": 1}`, `{"a": 1}`, `{}`},
+		{`{"a": 1}`, `{"a": 2}`, `{"a": 2}`},
+		{`{"a": 1, "b": 2}`, `{"a": 1}`, `{"b": null}`},
+		{`{}`, `{"a": {"b": 1}}`, `{"a": {"b": 1}}`},
+		{`{"a": {"b": 1, "c": 2}}`, `{"a": {"b": 1, "c": 3, "d": 4}}`, `{"a": {"c": 3, "d": 4}}`},
+		{`{"a": {"b": 1}}`, `{"a": {}}`, `{"a": {"b": null}}`},
+		{`{"a": {"b": 1}}`, `{"a": "b"}`, `{"a": "b"}`},
+		{`{"a": [1, 2]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{`{"a": [{"b": 1}]}`, `{"a": [{"b": 1}]}`, `{}`},
+		{`{"a": null}`, `{"a": 1}`, `{"a": 1}`},
+	}
+	for _, test := range tests {
+		got := mergePatch(parseJSON(t, test.oldObj), parseJSON(t, test.newObj))
+		if want := parseJSON(t, test.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("mergePatch(%s, %s) = %v, want %s", test.oldObj, test.newObj, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// +build linux
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setupProcessGroup runs the handler in its own process group, so that grandchildren are signaled with it
+func setupProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
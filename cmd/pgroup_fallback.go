@@ -0,0 +1,15 @@
+// +build !linux
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func setupProcessGroup(cmd *exec.Cmd) {
+}
+
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	return cmd.Process.Signal(sig)
+}
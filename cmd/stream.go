@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/xiaopal/kube-informer/pkg/subreaper"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	handlerModeStream = "stream"
+
+	streamRestartMinDelay = time.Second
+	streamRestartMaxDelay = time.Minute
+)
+
+// streamRequest is a line written to the stdin of the stream handler
+type streamRequest struct {
+	ID string `json:"id"`
+	*eventPayload
+	Retries int `json:"retries,omitempty"`
+}
+
+// streamAck is a line read from the stdout of the stream handler, it may carry the same actions as --handler-output=json
+type streamAck struct {
+	ID    string `json:"id"`
+	Error string `json:"error,omitempty"`
+	handlerAction
+}
+
+// streamHandler runs the handler command once, events are written to its stdin as json lines and acked on its stdout,
+// writes are handed to a writer goroutine so that a handler not reading its stdin blocks no more than the events written
+type streamHandler struct {
+	lock     sync.Mutex
+	nextID   uint64
+	writes   chan []byte
+	started  chan struct{}
+	stopped  chan struct{}
+	inFlight map[string]chan *streamAck
+}
+
+var handlerStream *streamHandler
+
+func newStreamHandler() *streamHandler {
+	return &streamHandler{started: make(chan struct{}), inFlight: map[string]chan *streamAck{}}
+}
+
+// run keeps the handler running until ctx is done, it is restarted with backoff once exited
+func (s *streamHandler) run(ctx context.Context, logger *log.Logger) {
+	delay := streamRestartMinDelay
+	for {
+		startTime := time.Now()
+		if err := s.runOnce(ctx, logger); err != nil {
+			logger.Printf("stream handler exited: %v", err)
+		} else {
+			logger.Printf("stream handler exited")
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(startTime) > streamRestartMaxDelay {
+			delay = streamRestartMinDelay
+		}
+		logger.Printf("restarting stream handler in %v", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		if delay *= 2; delay > streamRestartMaxDelay {
+			delay = streamRestartMaxDelay
+		}
+	}
+}
+
+// runOnce runs the handler until it exits, events in flight then fail and get retried
+func (s *streamHandler) runOnce(ctx context.Context, logger *log.Logger) error {
+	handler := exec.Command(handlerCommand[0], handlerCommand[1:]...)
+	handler.Env = append(os.Environ(), fmt.Sprintf("INFORMER_MAX_RETRIES=%d", handlerMaxRetries))
+	stdin, err := handler.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := handler.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := pipeStderr(handler, logger); err != nil {
+		return fmt.Errorf("failed to pipe stderr: %v", err)
+	}
+	// the subreaper is paused only to start and to wait for the handler, meanwhile it reaps the processes orphaned
+	subreaper.Pause()
+	setupProcessGroup(handler)
+	err = handler.Start()
+	subreaper.Resume()
+	if err != nil {
+		return err
+	}
+	logger.Printf("started stream handler pid %d", handler.Process.Pid)
+	writes, stopped := make(chan []byte), make(chan struct{})
+	go writeRequests(stdin, writes, stopped, logger)
+	s.lock.Lock()
+	s.writes, s.stopped = writes, stopped
+	close(s.started)
+	s.lock.Unlock()
+	acks := make(chan struct{})
+	go func() {
+		defer close(acks)
+		s.readAcks(stdout, logger)
+	}()
+	select {
+	case <-acks:
+	case <-ctx.Done():
+	}
+	// a handler closing its stdout is terminated unless it exits within --handler-kill-grace
+	waitCtx, cancel := context.WithTimeout(ctx, handlerKillGrace)
+	defer cancel()
+	subreaper.Pause()
+	err = waitHandler(waitCtx, handler, logger)
+	subreaper.Resume()
+	if err == context.DeadlineExceeded {
+		err = fmt.Errorf("stdout closed but still running")
+	}
+	s.lock.Lock()
+	s.writes, s.started = nil, make(chan struct{})
+	close(stopped)
+	for id, result := range s.inFlight {
+		close(result)
+		delete(s.inFlight, id)
+	}
+	s.lock.Unlock()
+	return err
+}
+
+// writeRequests writes to the handler stdin until the handler exits, stdin is closed once a write fails
+func writeRequests(stdin io.WriteCloser, writes <-chan []byte, stopped <-chan struct{}, logger *log.Logger) {
+	for {
+		select {
+		case data := <-writes:
+			if _, err := stdin.Write(data); err != nil {
+				logger.Printf("failed to write request: %v", err)
+				stdin.Close()
+				return
+			}
+		case <-stopped:
+			return
+		}
+	}
+}
+
+// readAcks dispatches acks to the events in flight, lines other than acks are logged
+func (s *streamHandler) readAcks(stdout io.Reader, logger *log.Logger) {
+	lines := bufio.NewScanner(stdout)
+	lines.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lines.Scan() {
+		ack := &streamAck{}
+		if err := json.Unmarshal(lines.Bytes(), ack); err != nil || ack.ID == "" {
+			logger.Println(lines.Text())
+			continue
+		}
+		s.lock.Lock()
+		result, ok := s.inFlight[ack.ID]
+		delete(s.inFlight, ack.ID)
+		s.lock.Unlock()
+		if ok {
+			result <- ack
+		} else if glog.V(2) {
+			logger.Printf("ignored ack of request %s, not in flight", ack.ID)
+		}
+	}
+}
+
+// request writes the event to the handler and waits for the ack
+func (s *streamHandler) request(ctx context.Context, payload *eventPayload, numRetries int) (*streamAck, error) {
+	s.lock.Lock()
+	started := s.started
+	s.lock.Unlock()
+	select {
+	case <-started:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	id := strconv.FormatUint(atomic.AddUint64(&s.nextID, 1), 10)
+	data, err := json.Marshal(&streamRequest{id, payload, numRetries})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+	result := make(chan *streamAck, 1)
+	s.lock.Lock()
+	writes, stopped := s.writes, s.stopped
+	if writes == nil {
+		s.lock.Unlock()
+		return nil, fmt.Errorf("stream handler not running")
+	}
+	s.inFlight[id] = result
+	s.lock.Unlock()
+	select {
+	case writes <- append(data, '\n'):
+	case <-stopped:
+		return nil, fmt.Errorf("stream handler exited")
+	case <-ctx.Done():
+		s.forget(id)
+		return nil, ctx.Err()
+	}
+	select {
+	case ack, ok := <-result:
+		if !ok {
+			return nil, fmt.Errorf("stream handler exited")
+		}
+		return ack, nil
+	case <-ctx.Done():
+		s.forget(id)
+		return nil, ctx.Err()
+	}
+}
+
+func (s *streamHandler) forget(id string) {
+	s.lock.Lock()
+	delete(s.inFlight, id)
+	s.lock.Unlock()
+}
+
+func (s *streamHandler) handle(ctx context.Context, obj *unstructured.Unstructured, payload *eventPayload, numRetries int, logger *log.Logger) error {
+	if handlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, handlerTimeout)
+		defer cancel()
+	}
+	ack, err := s.request(ctx, payload, numRetries)
+	if err == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %v", handlerTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	if ack.Error != "" {
+		return fmt.Errorf("handler failed: %s", ack.Error)
+	}
+	return ack.apply(obj, logger)
+}
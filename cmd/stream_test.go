@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xiaopal/kube-informer/pkg/informer"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// startStreamHandler runs the script as stream handler until the test ends
+func startStreamHandler(script string) (*streamHandler, func()) {
+	command, grace := handlerCommand, handlerKillGrace
+	handlerCommand, handlerKillGrace = []string{"sh", "-c", script}, 100*time.Millisecond
+	s, logger := newStreamHandler(), log.New(ioutil.Discard, "", 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.run(ctx, logger)
+	}()
+	return s, func() {
+		cancel()
+		<-done
+		handlerCommand, handlerKillGrace = command, grace
+	}
+}
+
+func streamPayload(size int) *eventPayload {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"data": strings.Repeat("x", size)}}
+	return &eventPayload{Event: informer.EventAdd, Object: obj}
+}
+
+func TestStreamHandlerAcks(t *testing.T) {
+	s, stop := startStreamHandler(`sed -u 's/^{"id":"\([0-9]*\)".*/{"id":"\1","requeueAfter":30}/'`)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for n := 0; n < 3; n++ {
+		ack, err := s.request(ctx, streamPayload(10), 0)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if string(ack.RequeueAfter) != "30" {
+			t.Errorf("ack = %+v, want requeueAfter 30", ack)
+		}
+	}
+}
+
+func TestStreamHandlerExited(t *testing.T) {
+	s, stop := startStreamHandler(`read line; exit 1`)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := s.request(ctx, streamPayload(10), 0); err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("request = %v, want stream handler exited", err)
+	}
+}
+
+func TestStreamHandlerStdoutClosed(t *testing.T) {
+	s, stop := startStreamHandler(`exec >&-; sleep 60`)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := s.request(ctx, streamPayload(10), 0); err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("request = %v, want stream handler exited", err)
+	}
+}
+
+func TestStreamHandlerWriteCanceled(t *testing.T) {
+	s, stop := startStreamHandler(`sleep 60`)
+	defer stop()
+	errs := make(chan error, 2)
+	for n := 0; n < 2; n++ {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			// larger than the pipe buffer, the write blocks as the handler reads nothing
+			_, err := s.request(ctx, streamPayload(1024*1024), 0)
+			errs <- err
+		}()
+	}
+	for n := 0; n < 2; n++ {
+		select {
+		case err := <-errs:
+			if err != context.DeadlineExceeded {
+				t.Errorf("request = %v, want %v", err, context.DeadlineExceeded)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("request blocked on a handler not reading its stdin")
+		}
+	}
+}
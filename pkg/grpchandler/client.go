@@ -0,0 +1,65 @@
+package grpchandler
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. handler.proto
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+
+	"google.golang.org/grpc"
+)
+
+//Client calls Handler.HandleEvent once for each event, the deadline and cancellation of the caller context apply to the call
+type Client struct {
+	conn    *grpc.ClientConn
+	handler HandlerClient
+}
+
+//NewClient connects to the handler listening on the unix socket, eg. `unix:///run/handler.sock`,
+//the connection is set up on demand and again once broken
+func NewClient(target string) (*Client, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	path := u.Path
+	if u.Scheme != "unix" || path == "" {
+		return nil, fmt.Errorf("unix:///path/to/socket required")
+	}
+	dialer := &net.Dialer{}
+	conn, err := grpc.Dial("passthrough:///"+path, grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, handler: NewHandlerClient(conn)}, nil
+}
+
+//HandleEvent calls the handler and returns the last ack, an empty ack if the handler acked nothing
+func (c *Client) HandleEvent(ctx context.Context, req *EventRequest) (*EventAck, error) {
+	stream, err := c.handler.HandleEvent(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	ack := &EventAck{}
+	for {
+		next, err := stream.Recv()
+		if err == io.EOF {
+			return ack, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		ack = next
+	}
+}
+
+//Close closes the connection, calls in flight fail
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,145 @@
+package grpchandler
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testHandler acts by the name of the object
+type testHandler struct {
+	deadlines chan bool
+	canceled  chan struct{}
+}
+
+func (h *testHandler) HandleEvent(req *EventRequest, stream Handler_HandleEventServer) error {
+	switch req.Name {
+	case "acks":
+		stream.Send(&EventAck{Error: "not yet"})
+		return stream.Send(&EventAck{RequeueAfter: ptypes.DurationProto(30 * time.Second)})
+	case "error":
+		return status.Error(codes.Internal, "handler crashed")
+	case "slow":
+		_, ok := stream.Context().Deadline()
+		h.deadlines <- ok
+		<-stream.Context().Done()
+		close(h.canceled)
+		return stream.Context().Err()
+	}
+	if req.Event != "add" || string(req.Object) != `{"kind":"ConfigMap"}` || req.Retries != 2 {
+		return stream.Send(&EventAck{Error: "unexpected request", Permanent: true})
+	}
+	return nil
+}
+
+func startTestServer(t *testing.T) (*Client, *testHandler, func()) {
+	dir, err := ioutil.TempDir("", "grpchandler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "handler.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	handler, server := &testHandler{deadlines: make(chan bool, 1), canceled: make(chan struct{})}, grpc.NewServer()
+	RegisterHandlerServer(server, handler)
+	go server.Serve(listener)
+	client, err := NewClient("unix://" + path)
+	if err != nil {
+		server.Stop()
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return client, handler, func() {
+		client.Close()
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	for _, target := range []string{"/run/handler.sock", "tcp://localhost:8080", "unix://", "%"} {
+		if _, err := NewClient(target); err == nil {
+			t.Errorf("NewClient(%q) succeeded, want error", target)
+		}
+	}
+}
+
+func TestHandleEvent(t *testing.T) {
+	client, _, stop := startTestServer(t)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ack, err := client.HandleEvent(ctx, &EventRequest{Event: "add", Name: "ok", Object: []byte(`{"kind":"ConfigMap"}`), Retries: 2})
+	if err != nil || ack.Error != "" || ack.Skip || ack.RequeueAfter != nil {
+		t.Errorf("HandleEvent(ok) = %v, %v, want an empty ack", ack, err)
+	}
+	ack, err = client.HandleEvent(ctx, &EventRequest{Name: "acks"})
+	if err != nil {
+		t.Fatalf("HandleEvent(acks) failed: %v", err)
+	}
+	if delay, _ := ptypes.Duration(ack.RequeueAfter); ack.Error != "" || delay != 30*time.Second {
+		t.Errorf("HandleEvent(acks) = %v, want the last ack", ack)
+	}
+	if _, err = client.HandleEvent(ctx, &EventRequest{Name: "error"}); status.Code(err) != codes.Internal {
+		t.Errorf("HandleEvent(error) = %v, want the handler status", err)
+	}
+}
+
+func TestHandleEventDeadline(t *testing.T) {
+	client, handler, stop := startTestServer(t)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := client.HandleEvent(ctx, &EventRequest{Name: "slow"}); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("HandleEvent(slow) = %v, want %v", err, codes.DeadlineExceeded)
+	}
+	if !<-handler.deadlines {
+		t.Errorf("handler got no deadline")
+	}
+	select {
+	case <-handler.canceled:
+	case <-time.After(5 * time.Second):
+		t.Errorf("handler not canceled once the deadline passed")
+	}
+}
+
+func TestHandleEventCanceled(t *testing.T) {
+	client, handler, stop := startTestServer(t)
+	defer stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.HandleEvent(ctx, &EventRequest{Name: "slow"})
+		result <- err
+	}()
+	if <-handler.deadlines {
+		t.Errorf("handler got a deadline, want none")
+	}
+	cancel()
+	select {
+	case err := <-result:
+		if status.Code(err) != codes.Canceled {
+			t.Errorf("HandleEvent(slow) = %v, want %v", err, codes.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("HandleEvent not canceled")
+	}
+	select {
+	case <-handler.canceled:
+	case <-time.After(5 * time.Second):
+		t.Errorf("handler not canceled")
+	}
+}
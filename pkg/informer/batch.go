@@ -0,0 +1,136 @@
+package informer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+//Event is an event handled in batches
+type Event struct {
+	Type       EventType
+	Object     *unstructured.Unstructured
+	OldObject  *unstructured.Unstructured
+	NumRetries int
+}
+
+//BatchHandler handles events at once, the errors are of each event, nil for all succeeded
+type BatchHandler func(ctx context.Context, events []Event) []error
+
+type batchRequest struct {
+	ctx    context.Context
+	event  Event
+	result chan error
+}
+
+// batch collects the events of the objects gathered from the queue, the batch handler runs
+// once each object is either waiting for its event to be handled or done
+type batch struct {
+	lock    sync.Mutex
+	handler BatchHandler
+	active  int
+	waiting []*batchRequest
+}
+
+type batchContextKey struct{}
+
+// handleBatchEvent is the Handler of informers with a BatchHandler, it hands the event to the batch of the context
+func handleBatchEvent(ctx context.Context, event EventType, obj, oldObj *unstructured.Unstructured, numRetries int) error {
+	b := ctx.Value(batchContextKey{}).(*batch)
+	req := &batchRequest{ctx, Event{event, obj, oldObj, numRetries}, make(chan error, 1)}
+	b.lock.Lock()
+	b.waiting = append(b.waiting, req)
+	b.flush()
+	b.lock.Unlock()
+	return <-req.result
+}
+
+// done is called once an object of the batch is handled
+func (b *batch) done() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.active--
+	b.flush()
+}
+
+// flush runs the batch handler once all objects still active are waiting, lock held
+func (b *batch) flush() {
+	if len(b.waiting) == 0 || len(b.waiting) < b.active {
+		return
+	}
+	requests := b.waiting
+	b.waiting = nil
+	go func() {
+		events := make([]Event, len(requests))
+		for index, req := range requests {
+			events[index] = req.event
+		}
+		errs := b.handler(requests[0].ctx, events)
+		for index, req := range requests {
+			if index < len(errs) {
+				req.result <- errs[index]
+			} else {
+				req.result <- nil
+			}
+		}
+	}()
+}
+
+// feedBatches passes items of the queue on, so that batches stop gathering once the window ends
+func (i *informer) feedBatches(ctx context.Context) <-chan interface{} {
+	items := make(chan interface{})
+	go func() {
+		defer close(items)
+		for {
+			item, quit := i.queue.Get()
+			if quit {
+				return
+			}
+			select {
+			case items <- item:
+			case <-ctx.Done():
+				i.queue.Done(item)
+				return
+			}
+		}
+	}()
+	return items
+}
+
+// processNextBatch gathers up to BatchSize objects for BatchWindow since the first one,
+// the objects are handled as usual while their events are handed to the batch handler at once
+func (i *informer) processNextBatch(ctx context.Context, items <-chan interface{}) bool {
+	first, ok := <-items
+	if !ok {
+		return false
+	}
+	gathered, timer := []interface{}{first}, time.NewTimer(i.BatchWindow)
+gather:
+	for len(gathered) < i.BatchSize {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				break gather
+			}
+			gathered = append(gathered, item)
+		case <-timer.C:
+			break gather
+		}
+	}
+	timer.Stop()
+	b := &batch{handler: i.BatchHandler, active: len(gathered)}
+	ctx = context.WithValue(ctx, batchContextKey{}, b)
+	wg := sync.WaitGroup{}
+	for _, item := range gathered {
+		wg.Add(1)
+		go func(item interface{}) {
+			defer wg.Done()
+			defer b.done()
+			i.processItem(ctx, item)
+		}(item)
+	}
+	wg.Wait()
+	return true
+}
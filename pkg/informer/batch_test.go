@@ -0,0 +1,102 @@
+package informer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+type batchRecorder struct {
+	lock    sync.Mutex
+	batches [][]string
+	fail    map[string]bool
+}
+
+func (r *batchRecorder) handle(ctx context.Context, events []Event) []error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	names, errs := []string{}, make([]error, len(events))
+	for index, event := range events {
+		name := event.Object.GetName()
+		names = append(names, name)
+		if r.fail[name] {
+			errs[index] = fmt.Errorf("%s failed", name)
+		}
+	}
+	r.batches = append(r.batches, names)
+	return errs
+}
+
+func TestBatchWaitsForActiveObjects(t *testing.T) {
+	recorder := &batchRecorder{fail: map[string]bool{"b": true}}
+	b := &batch{handler: recorder.handle, active: 3}
+	ctx := context.WithValue(context.Background(), batchContextKey{}, b)
+	results, wg := map[string]error{}, sync.WaitGroup{}
+	lock := sync.Mutex{}
+	for _, name := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			err := handleBatchEvent(ctx, EventAdd, testObject("ns", name, name, "1"), nil, 0)
+			lock.Lock()
+			results[name] = err
+			lock.Unlock()
+		}(name)
+	}
+	time.Sleep(50 * time.Millisecond)
+	recorder.lock.Lock()
+	handled := len(recorder.batches)
+	recorder.lock.Unlock()
+	if handled != 0 {
+		t.Fatalf("batch handled before all objects were waiting or done")
+	}
+	b.done()
+	wg.Wait()
+	if len(recorder.batches) != 1 || len(recorder.batches[0]) != 2 {
+		t.Fatalf("batches = %v, want one batch of a and b", recorder.batches)
+	}
+	if results["a"] != nil || results["b"] == nil {
+		t.Errorf("results = %v, want only b failed", results)
+	}
+}
+
+func TestProcessNextBatch(t *testing.T) {
+	recorder := &batchRecorder{fail: map[string]bool{"c": true}}
+	i := NewInformer(nil, Opts{
+		Logger:       log.New(ioutil.Discard, "", 0),
+		BatchHandler: recorder.handle,
+		BatchSize:    2,
+		BatchWindow:  50 * time.Millisecond,
+		MaxRetries:   3,
+	}).(*informer)
+	defer i.queue.ShutDown()
+	i.watches = informerWatchList{{informer: i, indexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})}}
+	for _, name := range []string{"a", "b", "c"} {
+		i.enqueue(objectKey{0, "ns/" + name}, &pendingEvent{event: EventDelete, obj: testObject("ns", name, name, "1")})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items := i.feedBatches(ctx)
+	for n := 0; n < 2; n++ {
+		if !i.processNextBatch(ctx, items) {
+			t.Fatalf("processNextBatch returned false")
+		}
+	}
+	if want := [][]string{{"a", "b"}, {"c"}}; !reflect.DeepEqual(recorder.batches, want) && !reflect.DeepEqual(recorder.batches, [][]string{{"b", "a"}, {"c"}}) {
+		t.Errorf("batches = %v, want %v", recorder.batches, want)
+	}
+	i.pendingLock.Lock()
+	_, failedPending := i.pending[objectKey{0, "ns/c"}]
+	pending := len(i.pending)
+	i.pendingLock.Unlock()
+	if !failedPending || pending != 1 {
+		t.Errorf("pending = %d, want only the failed event requeued", pending)
+	}
+}
@@ -0,0 +1,249 @@
+package informer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xiaopal/kube-informer/pkg/kubeclient"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CheckpointState holds the last handled state of objects, keyed by watch id and object key,
+// objects are stripped to apiVersion, kind, name, namespace, uid, resourceVersion and labels
+type CheckpointState map[string]map[string]*unstructured.Unstructured
+
+//Checkpoint interface, stores the handled state across restarts
+type Checkpoint interface {
+	Load() (CheckpointState, error)
+	Save(state CheckpointState) error
+}
+
+type fileCheckpoint struct {
+	path string
+}
+
+//NewFileCheckpoint stores the checkpoint as a json file
+func NewFileCheckpoint(path string) Checkpoint {
+	return &fileCheckpoint{path}
+}
+
+func (c *fileCheckpoint) Load() (CheckpointState, error) {
+	state := CheckpointState{}
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", c.path, err)
+	}
+	return state, nil
+}
+
+func (c *fileCheckpoint) Save(state CheckpointState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+type configMapCheckpoint struct {
+	client          kubeclient.Client
+	namespace, name string
+}
+
+const configMapCheckpointKey = "checkpoint.json"
+
+//NewConfigMapCheckpoint stores the checkpoint in a ConfigMap, it is limited to the size of ConfigMaps(1MB)
+func NewConfigMapCheckpoint(client kubeclient.Client, namespace, name string) Checkpoint {
+	return &configMapCheckpoint{client, namespace, name}
+}
+
+func (c *configMapCheckpoint) Load() (CheckpointState, error) {
+	state := CheckpointState{}
+	client, resource, err := c.client.DynamicClient("v1", "ConfigMap")
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := client.Resource(resource, c.namespace).Get(c.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, _, _ := unstructured.NestedString(configMap.Object, "data", configMapCheckpointKey)
+	if data == "" {
+		return state, nil
+	}
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, fmt.Errorf("failed to parse configmap %s/%s: %v", c.namespace, c.name, err)
+	}
+	return state, nil
+}
+
+func (c *configMapCheckpoint) Save(state CheckpointState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	client, resource, err := c.client.DynamicClient("v1", "ConfigMap")
+	if err != nil {
+		return err
+	}
+	resourceClient := client.Resource(resource, c.namespace)
+	configMap, err := resourceClient.Get(c.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		configMap = &unstructured.Unstructured{Object: map[string]interface{}{}}
+		configMap.SetAPIVersion("v1")
+		configMap.SetKind("ConfigMap")
+		configMap.SetName(c.name)
+		configMap.SetNamespace(c.namespace)
+		unstructured.SetNestedField(configMap.Object, string(data), "data", configMapCheckpointKey)
+		_, err = resourceClient.Create(configMap)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	unstructured.SetNestedField(configMap.Object, string(data), "data", configMapCheckpointKey)
+	_, err = resourceClient.Update(configMap)
+	return err
+}
+
+// id identifies the watch across restarts, unlike the index it does not depend on the order of watches
+func (w *informerWatch) id() string {
+	return fmt.Sprintf("%s/%s %s(%s) %s %s", w.APIVersion, w.Kind, strings.Join(watchNamespaces(w.Namespaces), ","), w.NamespaceSelector, w.LabelSelector, w.FieldSelector)
+}
+
+func checkpointObject(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	ret := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	ret.SetAPIVersion(obj.GetAPIVersion())
+	ret.SetKind(obj.GetKind())
+	ret.SetName(obj.GetName())
+	ret.SetNamespace(obj.GetNamespace())
+	ret.SetUID(obj.GetUID())
+	ret.SetResourceVersion(obj.GetResourceVersion())
+	ret.SetLabels(obj.GetLabels())
+	return ret
+}
+
+func (i *informer) loadCheckpoint() error {
+	state, err := i.Checkpoint.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint: %v", err)
+	}
+	i.checkpointLock.Lock()
+	defer i.checkpointLock.Unlock()
+	i.checkpointState = state
+	return nil
+}
+
+func (i *informer) saveCheckpoint() {
+	i.checkpointLock.Lock()
+	if !i.checkpointDirty {
+		i.checkpointLock.Unlock()
+		return
+	}
+	state := CheckpointState{}
+	for id, objects := range i.checkpointState {
+		state[id] = map[string]*unstructured.Unstructured{}
+		for key, obj := range objects {
+			state[id][key] = obj
+		}
+	}
+	i.checkpointDirty = false
+	i.checkpointLock.Unlock()
+	if err := i.Checkpoint.Save(state); err != nil {
+		i.Logger.Printf("failed to save checkpoint: %v", err)
+		i.checkpointLock.Lock()
+		i.checkpointDirty = true
+		i.checkpointLock.Unlock()
+	}
+}
+
+// checkpointed returns true if the object has been handled in the same resourceVersion,
+// resync events are never skipped
+func (w *informerWatch) checkpointed(event EventType, key string, obj *unstructured.Unstructured) bool {
+	i := w.informer
+	if i.Checkpoint == nil || event == EventResync {
+		return false
+	}
+	i.checkpointLock.Lock()
+	defer i.checkpointLock.Unlock()
+	handled := i.checkpointState[w.id()][key]
+	return handled != nil && handled.GetUID() == obj.GetUID() && handled.GetResourceVersion() == obj.GetResourceVersion()
+}
+
+// checkpoint records the handled object, or removes it if nil
+func (w *informerWatch) checkpoint(key string, obj *unstructured.Unstructured) {
+	i := w.informer
+	if i.Checkpoint == nil {
+		return
+	}
+	i.checkpointLock.Lock()
+	defer i.checkpointLock.Unlock()
+	id := w.id()
+	if obj == nil {
+		if _, ok := i.checkpointState[id][key]; ok {
+			delete(i.checkpointState[id], key)
+			i.checkpointDirty = true
+		}
+		return
+	}
+	if i.checkpointState[id] == nil {
+		i.checkpointState[id] = map[string]*unstructured.Unstructured{}
+	}
+	i.checkpointState[id][key], i.checkpointDirty = checkpointObject(obj), true
+}
+
+// checkpointDeletes emits delete events for checkpointed objects missing after the watch synced,
+// they have been deleted while not watching
+func (w *informerWatch) checkpointDeletes() {
+	i := w.informer
+	if i.Checkpoint == nil {
+		return
+	}
+	i.checkpointLock.Lock()
+	defer i.checkpointLock.Unlock()
+	for key, obj := range i.checkpointState[w.id()] {
+		if _, exists, err := w.indexer.GetByKey(key); err == nil && !exists {
+			i.Logger.Printf("%s deleted while not watching %s", key, w.name())
+			i.enqueue(objectKey{w.index, key}, &pendingEvent{event: EventDelete, obj: obj.DeepCopy()})
+		}
+	}
+}
+
+// checkpointDeletesAfterSync runs checkpointDeletes once a watch started while running has synced
+func (w *informerWatch) checkpointDeletesAfterSync(stopCh <-chan struct{}) {
+	if w.informer.Checkpoint == nil {
+		return
+	}
+	go func() {
+		if cache.WaitForCacheSync(stopCh, w.hasSynced) {
+			w.checkpointDeletes()
+		}
+	}()
+}
@@ -0,0 +1,106 @@
+package informer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func testObject(namespace, name, uid, resourceVersion string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetUID(types.UID(uid))
+	obj.SetResourceVersion(resourceVersion)
+	return obj
+}
+
+func TestFileCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint.json")
+	checkpoint := NewFileCheckpoint(path)
+
+	state, err := checkpoint.Load()
+	if err != nil || len(state) != 0 {
+		t.Fatalf("Load() of a missing file = %v, %v, want empty state", state, err)
+	}
+	tests := []CheckpointState{
+		{"v1/ConfigMap": {"a/x": checkpointObject(testObject("a", "x", "1", "10")), "b/y": checkpointObject(testObject("b", "y", "2", "20"))}},
+		{"v1/ConfigMap": {"a/x": checkpointObject(testObject("a", "x", "1", "11"))}, "v1/Secret": {}},
+		{},
+	}
+	for _, want := range tests {
+		if err := checkpoint.Save(want); err != nil {
+			t.Fatalf("Save(%v) failed: %v", want, err)
+		}
+		got, err := checkpoint.Load()
+		if err != nil {
+			t.Fatalf("Load() failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Load() = %v, want %v", got, want)
+		}
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Save() left %d files, want only the checkpoint", len(files))
+	}
+
+	if err := ioutil.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkpoint.Load(); err == nil {
+		t.Errorf("Load() of an invalid file succeeded")
+	}
+}
+
+func TestCheckpointed(t *testing.T) {
+	w := &informerWatch{
+		WatchOpts: WatchOpts{APIVersion: "v1", Kind: "ConfigMap"},
+		informer:  &informer{Opts: Opts{Checkpoint: NewFileCheckpoint("")}, checkpointState: CheckpointState{}},
+	}
+	handled := testObject("a", "x", "1", "10")
+	handled.SetLabels(map[string]string{"app": "x"})
+	handled.Object["data"] = map[string]interface{}{"k": "v"}
+	w.checkpoint("a/x", handled)
+	if stored := w.informer.checkpointState[w.id()]["a/x"]; stored.Object["data"] != nil || !reflect.DeepEqual(stored.GetLabels(), handled.GetLabels()) {
+		t.Errorf("checkpoint stored %v, want it stripped to identity and labels", stored)
+	}
+	tests := []struct {
+		event EventType
+		obj   *unstructured.Unstructured
+		want  bool
+	}{
+		{EventSync, testObject("a", "x", "1", "10"), true},
+		{EventUpdate, testObject("a", "x", "1", "10"), true},
+		{EventResync, testObject("a", "x", "1", "10"), false},
+		{EventUpdate, testObject("a", "x", "1", "11"), false},
+		{EventAdd, testObject("a", "x", "2", "10"), false},
+	}
+	for _, test := range tests {
+		if got := w.checkpointed(test.event, "a/x", test.obj); got != test.want {
+			t.Errorf("checkpointed(%s, %s) = %v, want %v", test.event, test.obj.GetResourceVersion(), got, test.want)
+		}
+	}
+	if !w.informer.checkpointDirty {
+		t.Errorf("checkpoint did not mark the state dirty")
+	}
+	w.checkpoint("a/x", nil)
+	if w.checkpointed(EventSync, "a/x", testObject("a", "x", "1", "10")) {
+		t.Errorf("checkpointed after the object was removed")
+	}
+}
@@ -0,0 +1,197 @@
+package informer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+//DeadLetter is an event given up after max retries
+type DeadLetter struct {
+	ID        string                     `json:"id"`
+	Time      time.Time                  `json:"time"`
+	Watch     string                     `json:"watch"`
+	Key       string                     `json:"key"`
+	Event     EventType                  `json:"event"`
+	Object    *unstructured.Unstructured `json:"object,omitempty"`
+	OldObject *unstructured.Unstructured `json:"oldObject,omitempty"`
+	Error     string                     `json:"error"`
+	Retries   int                        `json:"retries"`
+}
+
+//DeadLetterStore interface, keeps dead letters until they are requeued
+type DeadLetterStore interface {
+	Add(letter *DeadLetter) error
+	List() ([]*DeadLetter, error)
+	Remove(ids []string) error
+}
+
+type fileDeadLetterStore struct {
+	lock sync.Mutex
+	path string
+}
+
+//NewFileDeadLetterStore stores dead letters as json lines
+func NewFileDeadLetterStore(path string) DeadLetterStore {
+	return &fileDeadLetterStore{path: path}
+}
+
+func (s *fileDeadLetterStore) Add(letter *DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *fileDeadLetterStore) List() ([]*DeadLetter, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.list()
+}
+
+func (s *fileDeadLetterStore) list() ([]*DeadLetter, error) {
+	ret := []*DeadLetter{}
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return ret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	lines := bufio.NewScanner(f)
+	lines.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lines.Scan() {
+		if len(lines.Bytes()) == 0 {
+			continue
+		}
+		letter := &DeadLetter{}
+		if err := json.Unmarshal(lines.Bytes(), letter); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", s.path, err)
+		}
+		ret = append(ret, letter)
+	}
+	return ret, lines.Err()
+}
+
+func (s *fileDeadLetterStore) Remove(ids []string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	letters, err := s.list()
+	if err != nil {
+		return err
+	}
+	removed := map[string]bool{}
+	for _, id := range ids {
+		removed[id] = true
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	out := bufio.NewWriter(tmp)
+	for _, letter := range letters {
+		if removed[letter.ID] {
+			continue
+		}
+		data, err := json.Marshal(letter)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		out.Write(append(data, '\n'))
+	}
+	if err := out.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// deadLetter stores the event given up, objects other than deleted ones are the current state
+func (i *informer) deadLetter(key objectKey, e *pendingEvent, err error, numRetries int) {
+	if i.DeadLetters == nil {
+		return
+	}
+	watch, _ := i.getWatch(key.watchIndex)
+	letter := &DeadLetter{
+		ID:        string(uuid.NewUUID()),
+		Time:      time.Now(),
+		Watch:     watch.id(),
+		Key:       key.key,
+		Event:     e.event,
+		Object:    e.obj,
+		OldObject: e.oldObj,
+		Error:     err.Error(),
+		Retries:   numRetries,
+	}
+	if letter.Object == nil {
+		if obj, exists, _ := watch.indexer.GetByKey(key.key); exists {
+			letter.Object = obj.(*unstructured.Unstructured)
+		}
+	}
+	if err := i.DeadLetters.Add(letter); err != nil {
+		i.Logger.Printf("failed to store dead letter (%v %s): %v", key, e.event, err)
+	}
+}
+
+func (i *informer) ListDeadLetters() ([]*DeadLetter, error) {
+	if i.DeadLetters == nil {
+		return nil, fmt.Errorf("dead letters not enabled")
+	}
+	return i.DeadLetters.List()
+}
+
+// RequeueDeadLetters queues the dead letters again, all of them if no ids given,
+// dead letters of watches not found are kept, objects no longer cached are handled as stored
+func (i *informer) RequeueDeadLetters(ids []string) ([]*DeadLetter, error) {
+	letters, err := i.ListDeadLetters()
+	if err != nil {
+		return nil, err
+	}
+	selected := map[string]bool{}
+	for _, id := range ids {
+		selected[id] = true
+	}
+	watches := map[string]*informerWatch{}
+	for _, watch := range i.listWatches() {
+		watches[watch.id()] = watch
+	}
+	requeued, requeuedIDs := []*DeadLetter{}, []string{}
+	for _, letter := range letters {
+		watch, ok := watches[letter.Watch]
+		if !ok || (len(ids) > 0 && !selected[letter.ID]) {
+			continue
+		}
+		e := &pendingEvent{event: letter.Event, obj: letter.Object, oldObj: letter.OldObject}
+		i.enqueue(objectKey{watch.index, letter.Key}, e)
+		requeued, requeuedIDs = append(requeued, letter), append(requeuedIDs, letter.ID)
+	}
+	if len(requeuedIDs) == 0 {
+		return requeued, nil
+	}
+	return requeued, i.DeadLetters.Remove(requeuedIDs)
+}
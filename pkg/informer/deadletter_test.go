@@ -0,0 +1,108 @@
+package informer
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func deadLetterIDs(letters []*DeadLetter) []string {
+	ids := []string{}
+	for _, letter := range letters {
+		ids = append(ids, letter.ID)
+	}
+	return ids
+}
+
+// testDeadLetterStore returns a file store holding the letters, removed with the returned func
+func testDeadLetterStore(t *testing.T, letters ...*DeadLetter) (DeadLetterStore, func()) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewFileDeadLetterStore(filepath.Join(dir, "deadletters.jsonl"))
+	for _, letter := range letters {
+		if err := store.Add(letter); err != nil {
+			os.RemoveAll(dir)
+			t.Fatalf("Add(%s) failed: %v", letter.ID, err)
+		}
+	}
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func TestFileDeadLetterStoreRemove(t *testing.T) {
+	tests := []struct {
+		remove []string
+		want   []string
+	}{
+		{[]string{"missing"}, []string{"1", "2", "3"}},
+		{[]string{"2"}, []string{"1", "3"}},
+		{[]string{"1", "3", "missing"}, []string{"2"}},
+		{nil, []string{"1", "2", "3"}},
+	}
+	for _, test := range tests {
+		store, cleanup := testDeadLetterStore(t, &DeadLetter{ID: "1"}, &DeadLetter{ID: "2"}, &DeadLetter{ID: "3"})
+		if err := store.Remove(test.remove); err != nil {
+			t.Errorf("Remove(%v) failed: %v", test.remove, err)
+		}
+		letters, err := store.List()
+		if err != nil {
+			t.Errorf("List() failed: %v", err)
+		}
+		if got := deadLetterIDs(letters); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("List() after Remove(%v) = %v, want %v", test.remove, got, test.want)
+		}
+		cleanup()
+	}
+}
+
+func TestRequeueDeadLetters(t *testing.T) {
+	handled := map[string]string{}
+	i := NewInformer(nil, Opts{
+		Logger: log.New(ioutil.Discard, "", 0),
+		Handler: func(ctx context.Context, event EventType, obj, oldObj *unstructured.Unstructured, numRetries int) error {
+			handled[obj.GetName()] = obj.GetResourceVersion()
+			return nil
+		},
+	}).(*informer)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(testObject("a", "x", "1", "11"))
+	watch := &informerWatch{WatchOpts: WatchOpts{APIVersion: "v1", Kind: "ConfigMap"}, informer: i, indexer: indexer}
+	i.watches = informerWatchList{watch}
+	store, cleanup := testDeadLetterStore(t,
+		&DeadLetter{ID: "1", Watch: watch.id(), Key: "a/x", Event: EventUpdate, Object: testObject("a", "x", "1", "10")},
+		&DeadLetter{ID: "2", Watch: watch.id(), Key: "b/y", Event: EventAdd, Object: testObject("b", "y", "2", "20")},
+		&DeadLetter{ID: "3", Watch: "v1/Secret", Key: "c/z", Event: EventAdd, Object: testObject("c", "z", "3", "30")},
+		&DeadLetter{ID: "4", Watch: watch.id(), Key: "d/w", Event: EventAdd, Object: testObject("d", "w", "4", "40")},
+	)
+	defer cleanup()
+	i.DeadLetters = store
+
+	requeued, err := i.RequeueDeadLetters([]string{"1", "2", "3"})
+	if err != nil {
+		t.Fatalf("RequeueDeadLetters() failed: %v", err)
+	}
+	if got := deadLetterIDs(requeued); !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("requeued %v, want 1 and 2", got)
+	}
+	letters, _ := store.List()
+	if got := deadLetterIDs(letters); !reflect.DeepEqual(got, []string{"3", "4"}) {
+		t.Errorf("kept %v, want 3 of an unknown watch and 4 not selected", got)
+	}
+	for range requeued {
+		item, _ := i.queue.Get()
+		i.processItem(context.Background(), item)
+	}
+	i.queue.ShutDown()
+	// cached objects are handled in their current state, others as stored
+	if want := map[string]string{"x": "11", "y": "20"}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled %v, want %v", handled, want)
+	}
+}
@@ -0,0 +1,69 @@
+package informer
+
+import (
+	"fmt"
+	"time"
+)
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return fmt.Sprintf("permanent failure: %v", e.err)
+}
+
+//PermanentError fails the event without retries
+func PermanentError(err error) error {
+	return &permanentError{err}
+}
+
+//IsPermanentError func
+func IsPermanentError(err error) bool {
+	_, ok := err.(*permanentError)
+	return ok
+}
+
+type requeueAfter struct {
+	delay time.Duration
+}
+
+func (e *requeueAfter) Error() string {
+	return fmt.Sprintf("requeue after %v", e.delay)
+}
+
+//RequeueAfter succeeds the event, and handles the object again after the delay as a resync event
+func RequeueAfter(delay time.Duration) error {
+	return &requeueAfter{delay}
+}
+
+//RequeueDelay returns the delay of RequeueAfter errors
+func RequeueDelay(err error) (time.Duration, bool) {
+	if e, ok := err.(*requeueAfter); ok {
+		return e.delay, true
+	}
+	return 0, false
+}
+
+type skipError struct{}
+
+func (e *skipError) Error() string {
+	return "skipped"
+}
+
+//Skip succeeds the event without recording it to the checkpoint
+func Skip() error {
+	return &skipError{}
+}
+
+//IsSkip func
+func IsSkip(err error) bool {
+	_, ok := err.(*skipError)
+	return ok
+}
+
+// succeeded returns true for nil and errors not failing the event
+func succeeded(err error) bool {
+	_, requeue := RequeueDelay(err)
+	return err == nil || requeue || IsSkip(err)
+}
@@ -0,0 +1,91 @@
+package informer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// handleObject runs the handler for objects still present, in finalizer mode it adds the finalizer first,
+// and handles objects being deleted as delete events before removing the finalizer
+func (w *informerWatch) handleObject(ctx context.Context, event EventType, key objectKey, obj, oldObj *unstructured.Unstructured, numRetries int) error {
+	i := w.informer
+	if i.Finalizer == "" {
+		return i.Handler(ctx, event, obj, oldObj, numRetries)
+	}
+	if obj.GetDeletionTimestamp() != nil {
+		if !hasFinalizer(obj, i.Finalizer) {
+			return nil
+		}
+		// objects requeued keep the finalizer until they are handled again
+		err := i.Handler(ctx, EventDelete, obj, nil, numRetries)
+		if _, requeue := RequeueDelay(err); requeue || !succeeded(err) {
+			return err
+		}
+		if err := w.patchFinalizers(obj, removeFinalizer(obj.GetFinalizers(), i.Finalizer)); err != nil {
+			return fmt.Errorf("failed to remove finalizer: %v", err)
+		}
+		i.finalized.set(key, obj)
+		return nil
+	}
+	if !hasFinalizer(obj, i.Finalizer) {
+		if err := w.patchFinalizers(obj, append(obj.GetFinalizers(), i.Finalizer)); err != nil {
+			return fmt.Errorf("failed to add finalizer: %v", err)
+		}
+	}
+	if event == EventSync && i.SkipInitialSync {
+		return nil
+	}
+	return i.Handler(ctx, event, obj, oldObj, numRetries)
+}
+
+// isFinalizing returns true if the object is being deleted and still has the finalizer
+func (i *informer) isFinalizing(obj *unstructured.Unstructured) bool {
+	return i.Finalizer != "" && obj.GetDeletionTimestamp() != nil && hasFinalizer(obj, i.Finalizer)
+}
+
+// patchFinalizers replaces the finalizers of the object, conflicts if the object has changed since
+func (w *informerWatch) patchFinalizers(obj *unstructured.Unstructured, finalizers []string) error {
+	w.lock.Lock()
+	resourceClient := w.resourceClient
+	w.lock.Unlock()
+	if resourceClient == nil {
+		return fmt.Errorf("kind of %s not available", w.name())
+	}
+	if finalizers == nil {
+		finalizers = []string{}
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": obj.GetResourceVersion(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = resourceClient(obj.GetNamespace()).Patch(obj.GetName(), types.MergePatchType, patch)
+	return err
+}
+
+func hasFinalizer(obj *unstructured.Unstructured, finalizer string) bool {
+	for _, f := range obj.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFinalizer(finalizers []string, finalizer string) []string {
+	ret := []string{}
+	for _, f := range finalizers {
+		if f != finalizer {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
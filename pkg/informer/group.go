@@ -0,0 +1,122 @@
+package informer
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/xiaopal/kube-informer/pkg/kubeclient"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// informerGroup watches every listable and watchable kind of an api group, kinds discovered later are watched too
+type informerGroup struct {
+	WatchOpts
+	group, version string
+	kinds          map[schema.GroupKind]bool
+}
+
+func (g *informerGroup) name() string {
+	if g.version == "" {
+		return g.group
+	}
+	return fmt.Sprintf("%s/%s", g.group, g.version)
+}
+
+func (i *informer) WatchGroup(group string, version string, opts WatchOpts) error {
+	g := &informerGroup{WatchOpts: opts, group: group, version: version, kinds: map[schema.GroupKind]bool{}}
+	if err := i.watchGroupKinds(g); err != nil {
+		return err
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.groups = append(i.groups, g)
+	return nil
+}
+
+func (i *informer) listGroups() []*informerGroup {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return append([]*informerGroup{}, i.groups...)
+}
+
+// watchGroupKinds adds watches for kinds of the group not watched yet,
+// these watches wait for their kinds, so that they stop when the kinds (eg. CRDs) are removed
+func (i *informer) watchGroupKinds(g *informerGroup) error {
+	kinds, err := i.client.GroupKinds(g.group, g.version)
+	if err != nil {
+		return err
+	}
+	for _, gvk := range kinds {
+		if g.kinds[gvk.GroupKind()] {
+			continue
+		}
+		opts := g.WatchOpts
+		opts.APIVersion, opts.Kind = gvk.ToAPIVersionAndKind()
+		opts.WaitForKind = true
+		if err := i.WatchWithOpts(opts); err != nil {
+			return err
+		}
+		g.kinds[gvk.GroupKind()] = true
+	}
+	return nil
+}
+
+// pendingResource is a resource name not resolved yet, eg. the CRD is not installed
+type pendingResource struct {
+	WatchOpts
+	resource string
+}
+
+// WatchResource watches the kinds of a resource name, eg. `deployments.apps` or `all`,
+// names not known yet are resolved later if WaitForKind is set
+func (i *informer) WatchResource(resource string, opts WatchOpts) error {
+	if err := i.watchResourceKinds(resource, opts); err != nil {
+		if !opts.WaitForKind || !kubeclient.IsNoMatchError(err) {
+			return err
+		}
+		i.Logger.Printf("waiting for %s: %v", resource, err)
+		i.lock.Lock()
+		defer i.lock.Unlock()
+		i.resources = append(i.resources, &pendingResource{WatchOpts: opts, resource: resource})
+	}
+	return nil
+}
+
+func (i *informer) watchResourceKinds(resource string, opts WatchOpts) error {
+	kinds, err := i.client.ResolveResources(resource)
+	if err != nil {
+		return err
+	}
+	for _, gvk := range kinds {
+		opts.APIVersion, opts.Kind = gvk.ToAPIVersionAndKind()
+		if err := i.WatchWithOpts(opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveResources retries the pending resources, returns true if any is still not found
+func (i *informer) resolveResources() bool {
+	i.lock.Lock()
+	resources := i.resources
+	i.resources = nil
+	i.lock.Unlock()
+	noMatch, pending := false, []*pendingResource{}
+	for _, r := range resources {
+		err := i.watchResourceKinds(r.resource, r.WatchOpts)
+		if err == nil {
+			i.Logger.Printf("resource available, watching %s", r.resource)
+			continue
+		}
+		noMatch = noMatch || kubeclient.IsNoMatchError(err)
+		if glog.V(3) {
+			i.Logger.Printf("still waiting for %s: %v", r.resource, err)
+		}
+		pending = append(pending, r)
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.resources = append(pending, i.resources...)
+	return noMatch
+}
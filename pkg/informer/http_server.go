@@ -23,6 +23,7 @@ type HTTPServerLocations struct {
 	Health                        string
 	DefaultIndex, IndexPrefix     string
 	APIProxyPrefix, APIProxyAllow string
+	DeadLetters                   string
 }
 
 func writeJSON(res http.ResponseWriter, statusCode int, data interface{}) error {
@@ -38,9 +39,9 @@ func writeJSON(res http.ResponseWriter, statusCode int, data interface{}) error
 
 func handleHealthRequest(loc string, res http.ResponseWriter, req *http.Request, informer Informer) error {
 	if !informer.Active() {
-		return writeJSON(res, http.StatusServiceUnavailable, map[string]string{"status": "DOWN"})
+		return writeJSON(res, http.StatusServiceUnavailable, map[string]interface{}{"status": "DOWN", "watches": informer.Watches()})
 	}
-	return writeJSON(res, http.StatusOK, map[string]string{"status": "UP"})
+	return writeJSON(res, http.StatusOK, map[string]interface{}{"status": "UP", "watches": informer.Watches()})
 }
 
 func intParam(req *http.Request, name string, defaultValue int) int {
@@ -127,6 +128,35 @@ func handleIndexRequest(loc string, res http.ResponseWriter, req *http.Request,
 	return writeJSONList(res, req, list, "items")
 }
 
+// handleDeadLettersRequest lists dead letters, or requeues them on POST, by `id` params or all with `all` param
+func handleDeadLettersRequest(loc string, res http.ResponseWriter, req *http.Request, informer Informer) error {
+	if req.Method != "POST" {
+		letters, err := informer.ListDeadLetters()
+		if err != nil {
+			return fmt.Errorf("failed to list dead letters: %v", err)
+		}
+		list := make([]interface{}, len(letters))
+		for i, letter := range letters {
+			list[i] = letter
+		}
+		return writeJSONList(res, req, list, "items")
+	}
+	req.ParseForm()
+	ids := req.Form["id"]
+	if _, all := req.Form["all"]; len(ids) == 0 && !all {
+		return writeJSON(res, http.StatusBadRequest, map[string]string{"error": "id or all required"})
+	}
+	requeued, err := informer.RequeueDeadLetters(ids)
+	if err != nil {
+		return fmt.Errorf("failed to requeue dead letters: %v", err)
+	}
+	list := make([]interface{}, len(requeued))
+	for i, letter := range requeued {
+		list[i] = letter
+	}
+	return writeJSONList(res, req, list, "requeued")
+}
+
 func handleProxyRequest() {
 
 }
@@ -156,6 +186,9 @@ func (i *informer) EnableHTTPServerWithLocations(serverAddr string, locations HT
 	if location := locations.IndexPrefix; location != "" {
 		serverMux.HandleFunc(location, informerHandler(location, handleIndexRequest))
 	}
+	if location := locations.DeadLetters; location != "" {
+		serverMux.HandleFunc(location, informerHandler(location, handleDeadLettersRequest))
+	}
 	if location := locations.APIProxyPrefix; location != "" {
 		handler, err := apiProxyHandler(location, i.client.GetConfigOrDie(), locations.APIProxyAllow)
 		if err != nil {
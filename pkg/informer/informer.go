@@ -7,13 +7,17 @@ import (
 	"math"
 	"net/http"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 
+	"github.com/golang/glog"
 	"golang.org/x/time/rate"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	dynamic "k8s.io/client-go/deprecated-dynamic"
@@ -26,10 +30,80 @@ import (
 //Opts type
 type Opts struct {
 	Logger      *log.Logger
-	Handler     func(ctx context.Context, event EventType, obj *unstructured.Unstructured, numRetries int) error
+	Handler     func(ctx context.Context, event EventType, obj, oldObj *unstructured.Unstructured, numRetries int) error
 	MaxRetries  interface{}
 	RateLimiter workqueue.RateLimiter
 	Indexers    cache.Indexers
+	// ResolvePeriod is the interval to retry watches waiting for kinds
+	ResolvePeriod time.Duration
+	// SkipInitialSync suppresses sync events of the initial list
+	SkipInitialSync bool
+	// SkipResync drops resync events of unchanged objects, objects requeued by handlers are still handled as resync events
+	SkipResync bool
+	// PassOldObject keeps the old objects of updates for the handler, with FetchObject the objects fetched last time
+	PassOldObject bool
+	// UpdateFilter drops update events before they are queued if it returns false
+	UpdateFilter func(oldObj, newObj *unstructured.Unstructured) bool
+	// Finalizer is added to watched objects, deletions are handled before it is removed
+	Finalizer string
+	// Checkpoint stores the handled objects, events already handled before a restart are skipped
+	Checkpoint Checkpoint
+	// CheckpointPeriod is the interval to save the checkpoint
+	CheckpointPeriod time.Duration
+	// Debounce delays handling objects until no event arrived for the window
+	Debounce time.Duration
+	// DebounceMaxWait caps the delay since the first event, 10 times Debounce by default
+	DebounceMaxWait time.Duration
+	// BatchHandler handles up to BatchSize events at once, gathered for BatchWindow, instead of Handler,
+	// Workers is then the number of batches handled in parallel
+	BatchHandler BatchHandler
+	BatchSize    int
+	BatchWindow  time.Duration
+	// DeadLetters stores events given up after max retries
+	DeadLetters DeadLetterStore
+	// Workers is the number of events handled in parallel, events of the same object are never handled at once
+	Workers int
+}
+
+//WatchOpts type
+type WatchOpts struct {
+	APIVersion    string
+	Kind          string
+	Namespaces    []string
+	LabelSelector string
+	FieldSelector string
+	Resync        time.Duration
+	// NamespaceSelector watches namespaces matching the label selector, instead of Namespaces
+	NamespaceSelector string
+	// WaitForKind keeps retrying an unresolved kind (eg. CRD not installed yet) instead of failing,
+	// and stops the watch when the kind disappears
+	WaitForKind bool
+	// MetadataOnly lists and watches PartialObjectMetadata, only apiVersion, kind and metadata are cached
+	MetadataOnly bool
+	// FetchObject gets the full object just before the handler runs, useful with MetadataOnly,
+	// the old objects of updates are then the objects fetched last time, kept in memory with PassOldObject
+	FetchObject bool
+	// Transform runs before objects are stored in the indexer, eg. to prune unused fields
+	Transform func(obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+}
+
+//WatchState type
+type WatchState string
+
+const (
+	//WatchPending constant, waiting for the kind
+	WatchPending WatchState = "pending"
+	//WatchSyncing constant
+	WatchSyncing WatchState = "syncing"
+	//WatchActive constant
+	WatchActive WatchState = "active"
+)
+
+//WatchStatus type
+type WatchStatus struct {
+	Index int        `json:"index"`
+	Name  string     `json:"name"`
+	State WatchState `json:"state"`
 }
 
 //EventType type
@@ -42,22 +116,47 @@ const (
 	EventUpdate EventType = "update"
 	//EventDelete constant
 	EventDelete EventType = "delete"
+	//EventSync constant, objects of the initial list
+	EventSync EventType = "sync"
+	//EventResync constant, periodic resync of unchanged objects
+	EventResync EventType = "resync"
 )
 
 type informer struct {
 	Opts
-	active         bool
-	client         kubeclient.Client
-	queue          workqueue.RateLimitingInterface
-	deletedObjects objectMap
-	watches        informerWatchList
-	httpServer     *http.Server
+	active          int32
+	client          kubeclient.Client
+	queue           workqueue.RateLimitingInterface
+	pending         map[objectKey]pendingEvents
+	pendingLock     sync.Mutex
+	debouncing      map[objectKey]*debounceTimes
+	finalized       *objectMap
+	fetched         *objectMap
+	checkpointLock  sync.Mutex
+	checkpointState CheckpointState
+	checkpointDirty bool
+	lock            sync.RWMutex
+	watches         informerWatchList
+	groups          []*informerGroup
+	resources       []*pendingResource
+	stopCh          <-chan struct{}
+	httpServer      *http.Server
+	// kindsChanged is set once the list of a kind waited for is not found, so that discovery is refreshed
+	kindsChanged int32
 }
 type informerWatch struct {
-	name     string
-	informer *informer
-	index    int
-	watcher  cache.SharedIndexInformer
+	WatchOpts
+	// resolvedName is the string shown for the watch, updated once the kind is resolved
+	resolvedName     atomic.Value
+	informer         *informer
+	index            int
+	indexer          cache.Indexer
+	listWatch        func(namespace string) cache.ListerWatcher
+	resourceClient   func(namespace string) dynamic.ResourceInterface
+	namespaceTracker *watchReflector
+	lock             sync.Mutex
+	reflectors       map[string]*watchReflector
+	stopCh           <-chan struct{}
 }
 
 type informerWatchList []*informerWatch
@@ -67,12 +166,34 @@ type objectKey struct {
 	key        string
 }
 
-type eventKey struct {
-	objectKey
-	event EventType
+// objectMap is safe for concurrent use
+type objectMap struct {
+	lock    sync.Mutex
+	objects map[objectKey]*unstructured.Unstructured
+}
+
+func newObjectMap() *objectMap {
+	return &objectMap{objects: map[objectKey]*unstructured.Unstructured{}}
+}
+
+func (m *objectMap) get(key objectKey) (*unstructured.Unstructured, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	obj, ok := m.objects[key]
+	return obj, ok
+}
+
+func (m *objectMap) set(key objectKey, obj *unstructured.Unstructured) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.objects[key] = obj
 }
 
-type objectMap map[objectKey]*unstructured.Unstructured
+func (m *objectMap) remove(key objectKey) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.objects, key)
+}
 
 //DefaultRateLimiter func
 func DefaultRateLimiter(baseDelay time.Duration, maxDelay time.Duration, limitRate float64, limitBursts int) workqueue.RateLimiter {
@@ -94,28 +215,56 @@ func NewInformer(client kubeclient.Client, informerOpts Opts) Informer {
 	if opts.RateLimiter == nil {
 		opts.RateLimiter = DefaultRateLimiter(5*time.Millisecond, 1000*time.Second, math.MaxFloat64, math.MaxInt32)
 	}
+	if opts.ResolvePeriod <= 0 {
+		opts.ResolvePeriod = 10 * time.Second
+	}
 	if _, ok := opts.MaxRetries.(int); !ok {
 		opts.MaxRetries = 15
 	}
+	if opts.DebounceMaxWait <= 0 {
+		opts.DebounceMaxWait = 10 * opts.Debounce
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.BatchHandler != nil && opts.BatchSize > 0 {
+		if opts.BatchWindow <= 0 {
+			opts.BatchWindow = time.Second
+		}
+		opts.Handler = handleBatchEvent
+	}
+	if opts.CheckpointPeriod <= 0 {
+		opts.CheckpointPeriod = 5 * time.Second
+	}
 	if opts.Handler == nil {
-		opts.Handler = func(ctx context.Context, event EventType, obj *unstructured.Unstructured, numRetries int) error {
+		opts.Handler = func(ctx context.Context, event EventType, obj, oldObj *unstructured.Unstructured, numRetries int) error {
 			opts.Logger.Printf("%s %s.%s: %s/%s", event, obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName())
 			return nil
 		}
 	}
 	return &informer{
-		Opts:           opts,
-		client:         client,
-		queue:          workqueue.NewRateLimitingQueue(opts.RateLimiter),
-		deletedObjects: objectMap{},
-		watches:        informerWatchList{},
+		Opts:            opts,
+		client:          client,
+		queue:           workqueue.NewRateLimitingQueue(opts.RateLimiter),
+		pending:         map[objectKey]pendingEvents{},
+		debouncing:      map[objectKey]*debounceTimes{},
+		finalized:       newObjectMap(),
+		fetched:         newObjectMap(),
+		checkpointState: CheckpointState{},
+		watches:         informerWatchList{},
 	}
 }
 
 //Informer interface
 type Informer interface {
 	Watch(apiVersion string, kind string, namespace string, labelSelector string, fieldSelector string, resync time.Duration) error
+	WatchWithOpts(opts WatchOpts) error
+	WatchGroup(group string, version string, opts WatchOpts) error
+	WatchResource(resource string, opts WatchOpts) error
 	GetIndexer(watchIndex int) (cache.Indexer, bool)
+	Watches() []WatchStatus
+	ListDeadLetters() ([]*DeadLetter, error)
+	RequeueDeadLetters(ids []string) ([]*DeadLetter, error)
 	Active() bool
 	Run(ctx context.Context) error
 	EnableIndexServer(serverAddr string) *http.ServeMux
@@ -123,35 +272,45 @@ type Informer interface {
 }
 
 func (i *informer) Watch(apiVersion string, kind string, namespace string, labelSelector string, fieldSelector string, resync time.Duration) error {
-	client, resource, err := i.client.DynamicClient(apiVersion, kind)
-	if err != nil {
-		return err
+	return i.WatchWithOpts(WatchOpts{
+		APIVersion:    apiVersion,
+		Kind:          kind,
+		Namespaces:    []string{namespace},
+		LabelSelector: labelSelector,
+		FieldSelector: fieldSelector,
+		Resync:        resync,
+	})
+}
+
+func (i *informer) WatchWithOpts(opts WatchOpts) error {
+	watch := &informerWatch{
+		WatchOpts:  opts,
+		informer:   i,
+		indexer:    cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, i.Indexers),
+		reflectors: map[string]*watchReflector{},
 	}
-	if !resource.Namespaced {
-		namespace = metav1.NamespaceAll
+	watch.resolvedName.Store(fmt.Sprintf("%s.%s", opts.Kind, opts.APIVersion))
+	if err := watch.start(); err != nil {
+		if !opts.WaitForKind {
+			return err
+		}
+		i.Logger.Printf("waiting for %s: %v", watch.name(), err)
 	}
-	resourceClient, resourcePluralName := client.Resource(resource, namespace), resource.Name
-	watch := &informerWatch{
-		name:     fmt.Sprintf("%s/%s %s %s", namespace, resourcePluralName, labelSelector, fieldSelector),
-		informer: i,
-		index:    len(i.watches),
-		watcher: cache.NewSharedIndexInformer(
-			newListWatcherFromResourceClient(resourceClient, labelSelector, fieldSelector),
-			&unstructured.Unstructured{},
-			resync,
-			i.Indexers,
-		),
-	}
-	watch.watcher.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    watch.handleAdd,
-		DeleteFunc: watch.handleDelete,
-		UpdateFunc: watch.handleUpdate,
-	})
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	watch.index = len(i.watches)
 	i.watches = append(i.watches, watch)
+	if i.stopCh != nil {
+		i.Logger.Printf("watching %s", watch.name())
+		watch.run(i.stopCh)
+		if watch.listWatch != nil {
+			watch.checkpointDeletesAfterSync(i.stopCh)
+		}
+	}
 	return nil
 }
 
-func newListWatcherFromResourceClient(resourceClient dynamic.ResourceInterface, labelSelector string, fieldSelector string) *cache.ListWatch {
+func newListWatcherFromResourceClient(resourceClient dynamic.ResourceInterface, labelSelector string, fieldSelector string, transform func(*unstructured.Unstructured) *unstructured.Unstructured) *cache.ListWatch {
 	listOptions := func(options metav1.ListOptions) metav1.ListOptions {
 		if labelSelector != "" {
 			options.LabelSelector = labelSelector
@@ -162,23 +321,110 @@ func newListWatcherFromResourceClient(resourceClient dynamic.ResourceInterface,
 		return options
 	}
 	listFunc := func(options metav1.ListOptions) (runtime.Object, error) {
-		return resourceClient.List(listOptions(options))
+		list, err := resourceClient.List(listOptions(options))
+		if err == nil && transform != nil {
+			if list, ok := list.(*unstructured.UnstructuredList); ok {
+				for i := range list.Items {
+					list.Items[i] = *transform(&list.Items[i])
+				}
+			}
+		}
+		return list, err
 	}
 	watchFunc := func(options metav1.ListOptions) (watch.Interface, error) {
-		return resourceClient.Watch(listOptions(options))
+		w, err := resourceClient.Watch(listOptions(options))
+		if err == nil && transform != nil {
+			w = watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+				if obj, ok := event.Object.(*unstructured.Unstructured); ok {
+					event.Object = transform(obj)
+				}
+				return event, true
+			})
+		}
+		return w, err
 	}
 	return &cache.ListWatch{ListFunc: listFunc, WatchFunc: watchFunc}
 }
 
 func (i *informer) Active() bool {
-	return i.active
+	return atomic.LoadInt32(&i.active) != 0
 }
 
 func (i *informer) GetIndexer(watchIndex int) (cache.Indexer, bool) {
+	watch, ok := i.getWatch(watchIndex)
+	if !ok {
+		return nil, false
+	}
+	return watch.indexer, true
+}
+
+func (i *informer) getWatch(watchIndex int) (*informerWatch, bool) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
 	if watchIndex < 0 || watchIndex >= len(i.watches) {
 		return nil, false
 	}
-	return i.watches[watchIndex].watcher.GetIndexer(), true
+	return i.watches[watchIndex], true
+}
+
+func (i *informer) listWatches() informerWatchList {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return append(informerWatchList{}, i.watches...)
+}
+
+func (i *informer) Watches() []WatchStatus {
+	watches := i.listWatches()
+	ret := make([]WatchStatus, len(watches))
+	for index, watch := range watches {
+		ret[index] = watch.status()
+	}
+	return ret
+}
+
+// resolveWatches retries watches waiting for kinds, and stops watches whose kind disappeared,
+// cached discovery is only refreshed once a kind waited for is not found
+func (i *informer) resolveWatches() {
+	for _, group := range i.listGroups() {
+		if err := i.watchGroupKinds(group); err != nil {
+			i.Logger.Printf("failed to discover group %s: %v", group.name(), err)
+		}
+	}
+	changed := atomic.SwapInt32(&i.kindsChanged, 0) != 0
+	if !i.resolvePending() && !changed {
+		return
+	}
+	i.client.ResetRESTMapper()
+	i.resolvePending()
+	for _, watch := range i.listWatches() {
+		if !watch.WaitForKind || watch.status().State == WatchPending {
+			continue
+		}
+		if _, _, err := i.client.APIResource(watch.APIVersion, watch.Kind); kubeclient.IsNoMatchError(err) {
+			i.Logger.Printf("kind disappeared, stop watching %s: %v", watch.name(), err)
+			watch.stop()
+		}
+	}
+}
+
+// resolvePending starts the watches and resources waiting for kinds, returns true if any kind is still not found
+func (i *informer) resolvePending() bool {
+	noMatch := i.resolveResources()
+	for _, watch := range i.listWatches() {
+		if !watch.WaitForKind || watch.status().State != WatchPending {
+			continue
+		}
+		if err := watch.start(); err == nil {
+			i.Logger.Printf("kind available, watching %s", watch.name())
+			watch.checkpointDeletesAfterSync(i.stopCh)
+		} else {
+			noMatch = noMatch || kubeclient.IsNoMatchError(err)
+			if glog.V(3) {
+				i.Logger.Printf("still waiting for %s: %v", watch.name(), err)
+			}
+		}
+	}
+	return noMatch
 }
 
 func (w *informerWatch) handleAdd(obj interface{}) {
@@ -186,7 +432,18 @@ func (w *informerWatch) handleAdd(obj interface{}) {
 	if err != nil {
 		panic(err)
 	}
-	w.informer.queue.Add(eventKey{objectKey{w.index, key}, EventAdd})
+	w.informer.enqueue(objectKey{w.index, key}, &pendingEvent{event: EventAdd})
+}
+
+func (w *informerWatch) handleSync(obj interface{}) {
+	if w.informer.SkipInitialSync && w.informer.Finalizer == "" {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		panic(err)
+	}
+	w.informer.enqueue(objectKey{w.index, key}, &pendingEvent{event: EventSync})
 }
 
 func (w *informerWatch) handleDelete(obj interface{}) {
@@ -195,8 +452,14 @@ func (w *informerWatch) handleDelete(obj interface{}) {
 		panic(err)
 	}
 
-	w.informer.deletedObjects[objectKey{w.index, key}] = obj.(*unstructured.Unstructured).DeepCopy()
-	w.informer.queue.Add(eventKey{objectKey{w.index, key}, EventDelete})
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	deleted := &pendingEvent{event: EventDelete}
+	if obj, ok := obj.(*unstructured.Unstructured); ok {
+		deleted.obj = obj.DeepCopy()
+	}
+	w.informer.enqueue(objectKey{w.index, key}, deleted)
 }
 
 func (w *informerWatch) handleUpdate(oldObj, newObj interface{}) {
@@ -204,7 +467,33 @@ func (w *informerWatch) handleUpdate(oldObj, newObj interface{}) {
 	if err != nil {
 		panic(err)
 	}
-	w.informer.queue.Add(eventKey{objectKey{w.index, key}, EventUpdate})
+	updated := &pendingEvent{event: EventUpdate}
+	if sameResourceVersion(oldObj, newObj) {
+		if obj, ok := newObj.(*unstructured.Unstructured); ok && w.informer.SkipResync && !w.informer.isFinalizing(obj) {
+			return
+		}
+		updated.event = EventResync
+	} else if old, ok := oldObj.(*unstructured.Unstructured); ok {
+		if newObj, ok := newObj.(*unstructured.Unstructured); ok && w.informer.UpdateFilter != nil && !w.informer.UpdateFilter(old, newObj) && !w.informer.isFinalizing(newObj) {
+			return
+		}
+		if w.informer.PassOldObject {
+			updated.oldObj = old.DeepCopy()
+		}
+	}
+	w.informer.enqueue(objectKey{w.index, key}, updated)
+}
+
+func sameResourceVersion(oldObj, newObj interface{}) bool {
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return false
+	}
+	newMeta, err := meta.Accessor(newObj)
+	if err != nil {
+		return false
+	}
+	return oldMeta.GetResourceVersion() != "" && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion()
 }
 
 func (i *informer) processNextItem(ctx context.Context) bool {
@@ -212,33 +501,95 @@ func (i *informer) processNextItem(ctx context.Context) bool {
 	if quit {
 		return false
 	}
+	i.processItem(ctx, item)
+	return true
+}
+
+func (i *informer) processItem(ctx context.Context, item interface{}) {
 	defer i.queue.Done(item)
-	eventKey, numRetries := item.(eventKey), i.queue.NumRequeues(item)
-	watcher := i.watches[eventKey.watchIndex].watcher
-	obj, exists, err := watcher.GetIndexer().GetByKey(eventKey.key)
-	if err == nil {
-		if !exists {
-			if _, ok := i.deletedObjects[eventKey.objectKey]; !ok {
-				//logger.Printf("no last known state found for (%v)", eventKey)
-				i.queue.Forget(item)
-				return true
+	key, numRetries := item.(objectKey), i.queue.NumRequeues(item)
+	if wait := i.debounceWait(key); wait > 0 {
+		i.queue.AddAfter(item, wait)
+		return
+	}
+	pending := i.takePending(key)
+	for index, e := range pending {
+		e.handled = true
+		err := i.processEvent(ctx, key, e, numRetries)
+		if delay, ok := RequeueDelay(err); ok {
+			// events following handle the object again anyway, objects deleted are not handled again
+			if index == len(pending)-1 && e.event != EventDelete {
+				i.requeuePending(key, pendingEvents{{event: EventResync}})
+				i.queue.AddAfter(item, delay)
 			}
-			err = i.Handler(ctx, EventDelete, i.deletedObjects[eventKey.objectKey], numRetries)
-		} else {
-			err = i.Handler(ctx, eventKey.event, obj.(*unstructured.Unstructured).DeepCopy(), numRetries)
+			continue
+		}
+		if succeeded(err) {
+			continue
 		}
-	}
-	if err != nil {
 		maxRetries, _ := i.MaxRetries.(int)
-		i.Logger.Printf("error processing (%v, retries %v/%v): %v", eventKey, numRetries, maxRetries, err)
-		if maxRetries < 0 || numRetries < maxRetries {
+		i.Logger.Printf("error processing (%v %s, retries %v/%v): %v", key, e.event, numRetries, maxRetries, err)
+		if !IsPermanentError(err) && (maxRetries < 0 || numRetries < maxRetries) {
+			i.requeuePending(key, pending[index:])
 			i.queue.AddRateLimited(item)
-			return true
+			return
 		}
+		i.deadLetter(key, e, err, numRetries)
+	}
+	i.queue.Forget(item)
+}
+
+func (i *informer) processEvent(ctx context.Context, key objectKey, e *pendingEvent, numRetries int) error {
+	watch, _ := i.getWatch(key.watchIndex)
+	if e.event == EventDelete {
+		i.fetched.remove(key)
+		if _, finalized := i.finalized.get(key); finalized {
+			i.finalized.remove(key)
+			watch.checkpoint(key.key, nil)
+			return nil
+		}
+		if e.obj == nil {
+			//logger.Printf("no last known state found for (%v)", key)
+			return nil
+		}
+		err := i.Handler(ctx, EventDelete, e.obj, nil, numRetries)
+		if succeeded(err) && !IsSkip(err) {
+			watch.checkpoint(key.key, nil)
+		}
+		return err
+	}
+	obj, exists, err := watch.indexer.GetByKey(key.key)
+	if err != nil {
+		return err
 	}
 	if !exists {
-		delete(i.deletedObjects, eventKey.objectKey)
+		// dead letters requeued are handled even if the object is gone since
+		if e.obj != nil {
+			return i.Handler(ctx, e.event, e.obj.DeepCopy(), e.oldObj, numRetries)
+		}
+		// objects already gone are handled by the delete events following
+		return nil
 	}
-	i.queue.Forget(item)
-	return true
+	if watch.checkpointed(e.event, key.key, obj.(*unstructured.Unstructured)) {
+		return nil
+	}
+	target, oldObj := obj.(*unstructured.Unstructured).DeepCopy(), e.oldObj
+	if watch.FetchObject {
+		// skip objects already gone, delete events follow
+		if target, err = watch.fetchObject(target); err != nil || target == nil {
+			return err
+		}
+		// cached objects may be stripped, eg. by MetadataOnly, so updates are compared with the object fetched last time
+		if oldObj != nil {
+			oldObj, _ = i.fetched.get(key)
+		}
+	}
+	err = watch.handleObject(ctx, e.event, key, target, oldObj, numRetries)
+	if succeeded(err) && !IsSkip(err) {
+		if watch.FetchObject && i.PassOldObject {
+			i.fetched.set(key, target)
+		}
+		watch.checkpoint(key.key, obj.(*unstructured.Unstructured))
+	}
+	return err
 }
@@ -0,0 +1,134 @@
+package informer
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestProcessItemRequeueAfter(t *testing.T) {
+	tests := []struct {
+		event      EventType
+		wantResync bool
+	}{
+		{EventAdd, true},
+		{EventUpdate, true},
+		{EventResync, true},
+		{EventDelete, false},
+	}
+	for _, test := range tests {
+		handled := []EventType{}
+		i := NewInformer(nil, Opts{
+			Logger: log.New(ioutil.Discard, "", 0),
+			Handler: func(ctx context.Context, event EventType, obj, oldObj *unstructured.Unstructured, numRetries int) error {
+				handled = append(handled, event)
+				return RequeueAfter(time.Hour)
+			},
+		}).(*informer)
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		obj := testObject("ns", "a", "1", "10")
+		if test.event != EventDelete {
+			indexer.Add(obj)
+		}
+		i.watches = informerWatchList{{informer: i, indexer: indexer}}
+		key := objectKey{0, "ns/a"}
+		i.enqueue(key, &pendingEvent{event: test.event, obj: obj})
+		item, _ := i.queue.Get()
+		i.processItem(context.Background(), item)
+		i.queue.ShutDown()
+
+		if len(handled) != 1 || handled[0] != test.event {
+			t.Errorf("%s: handled %v, want the event once", test.event, handled)
+		}
+		pending := i.takePending(key)
+		if test.wantResync && (len(pending) != 1 || pending[0].event != EventResync) {
+			t.Errorf("%s: pending %v after requeue, want a resync", test.event, pending)
+		}
+		if !test.wantResync && len(pending) != 0 {
+			t.Errorf("%s: pending %v after requeue, want none", test.event, pending)
+		}
+	}
+}
+
+func TestHandleUpdateSkipResync(t *testing.T) {
+	finalizing := testObject("ns", "a", "1", "10")
+	finalizing.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	finalizing.SetFinalizers([]string{"example.com/cleanup"})
+	tests := []struct {
+		skipResync bool
+		finalizer  string
+		oldObj     *unstructured.Unstructured
+		newObj     *unstructured.Unstructured
+		want       []EventType
+	}{
+		{false, "", testObject("ns", "a", "1", "10"), testObject("ns", "a", "1", "10"), []EventType{EventResync}},
+		{true, "", testObject("ns", "a", "1", "10"), testObject("ns", "a", "1", "10"), nil},
+		{true, "", testObject("ns", "a", "1", "10"), testObject("ns", "a", "1", "11"), []EventType{EventUpdate}},
+		{true, "example.com/cleanup", finalizing, finalizing, []EventType{EventResync}},
+	}
+	for index, test := range tests {
+		i := NewInformer(nil, Opts{SkipResync: test.skipResync, Finalizer: test.finalizer}).(*informer)
+		w := &informerWatch{informer: i}
+		w.handleUpdate(test.oldObj, test.newObj)
+		i.queue.ShutDown()
+
+		got := []EventType(nil)
+		for _, e := range i.takePending(objectKey{0, "ns/a"}) {
+			got = append(got, e.event)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("#%d: got %v, want %v", index, got, test.want)
+		}
+	}
+}
+
+func TestHandleUpdatePassOldObject(t *testing.T) {
+	for _, passOldObject := range []bool{false, true} {
+		i := NewInformer(nil, Opts{PassOldObject: passOldObject}).(*informer)
+		w := &informerWatch{informer: i}
+		w.handleUpdate(testObject("ns", "a", "1", "10"), testObject("ns", "a", "1", "11"))
+		i.queue.ShutDown()
+
+		pending := i.takePending(objectKey{0, "ns/a"})
+		if len(pending) != 1 || (pending[0].oldObj != nil) != passOldObject {
+			t.Errorf("PassOldObject=%v: pending %v", passOldObject, pending)
+		}
+	}
+}
+
+func TestRemoveReflector(t *testing.T) {
+	tests := []struct {
+		finalizer string
+		want      []EventType
+	}{
+		{"", []EventType{EventDelete}},
+		{"example.com/cleanup", nil},
+	}
+	for _, test := range tests {
+		i := NewInformer(nil, Opts{Finalizer: test.finalizer}).(*informer)
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		indexer.Add(testObject("ns", "a", "1", "10"))
+		indexer.Add(testObject("other", "a", "2", "10"))
+		w := &informerWatch{informer: i, indexer: indexer, reflectors: map[string]*watchReflector{"ns": {namespace: "ns"}}}
+		w.removeReflector("ns")
+		i.queue.ShutDown()
+
+		got := []EventType(nil)
+		for _, e := range i.takePending(objectKey{0, "ns/a"}) {
+			got = append(got, e.event)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("finalizer %q: got %v, want %v", test.finalizer, got, test.want)
+		}
+		if keys := indexer.ListKeys(); !reflect.DeepEqual(keys, []string{"other/a"}) {
+			t.Errorf("finalizer %q: cached %v, want other/a only", test.finalizer, keys)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package informer
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// pendingEvent is an event waiting in the queue, objects other than deleted ones are read from the indexer when handled
+type pendingEvent struct {
+	event EventType
+	// obj is the last known state of deleted objects, or the object stored with a requeued dead letter
+	obj *unstructured.Unstructured
+	// oldObj is the state before the first coalesced update
+	oldObj *unstructured.Unstructured
+	// handled is set once the event was given to the handler, eg. before it failed and got requeued
+	handled bool
+}
+
+// pendingEvents of an object are delivered in order, a delete is followed by at most one event of the new object
+type pendingEvents []*pendingEvent
+
+// coalesce appends the event, events of the same object state are merged into the pending one:
+// add+update is add, update+update keeps the first old state, add+delete is nothing unless the add was handled already,
+// delete+add is delivered as both
+func (p pendingEvents) coalesce(e *pendingEvent) pendingEvents {
+	if len(p) == 0 {
+		return pendingEvents{e}
+	}
+	last := p[len(p)-1]
+	if last.event == EventDelete {
+		if e.event == EventDelete {
+			return p
+		}
+		return append(p, e)
+	}
+	ret := append(pendingEvents{}, p[:len(p)-1]...)
+	switch e.event {
+	case EventDelete:
+		// added objects not handled yet are never seen by the handler
+		if last.event == EventAdd && !last.handled {
+			return ret
+		}
+		return append(ret, e)
+	case EventUpdate:
+		if last.event == EventResync {
+			return append(ret, e)
+		}
+	}
+	return p
+}
+
+func (i *informer) enqueue(key objectKey, e *pendingEvent) {
+	i.pendingLock.Lock()
+	defer i.pendingLock.Unlock()
+	if pending := i.pending[key].coalesce(e); len(pending) > 0 {
+		i.pending[key] = pending
+	} else {
+		delete(i.pending, key)
+	}
+	if i.Debounce > 0 {
+		now, times := time.Now(), i.debouncing[key]
+		if times == nil {
+			times = &debounceTimes{first: now}
+			i.debouncing[key] = times
+		}
+		times.last = now
+		i.queue.AddAfter(key, i.Debounce)
+		return
+	}
+	i.queue.Add(key)
+}
+
+// debounceTimes are the times of the first and the last event since the object was handled
+type debounceTimes struct {
+	first, last time.Time
+}
+
+// debounceWait returns how long to wait until the object is quiet for the debounce window,
+// or until it has waited for the max wait since the first event
+func (i *informer) debounceWait(key objectKey) time.Duration {
+	if i.Debounce <= 0 {
+		return 0
+	}
+	i.pendingLock.Lock()
+	defer i.pendingLock.Unlock()
+	times := i.debouncing[key]
+	if times == nil {
+		return 0
+	}
+	now := time.Now()
+	wait := times.last.Add(i.Debounce).Sub(now)
+	if maxWait := times.first.Add(i.DebounceMaxWait).Sub(now); maxWait < wait {
+		wait = maxWait
+	}
+	if wait <= 0 {
+		delete(i.debouncing, key)
+		return 0
+	}
+	return wait
+}
+
+// takePending removes the pending events of the object, events arriving while they are handled are queued again
+func (i *informer) takePending(key objectKey) pendingEvents {
+	i.pendingLock.Lock()
+	defer i.pendingLock.Unlock()
+	pending := i.pending[key]
+	delete(i.pending, key)
+	return pending
+}
+
+// requeuePending puts events failed to handle before the events arrived since
+func (i *informer) requeuePending(key objectKey, failed pendingEvents) {
+	i.pendingLock.Lock()
+	defer i.pendingLock.Unlock()
+	pending := failed
+	for _, e := range i.pending[key] {
+		pending = pending.coalesce(e)
+	}
+	if len(pending) > 0 {
+		i.pending[key] = pending
+	} else {
+		delete(i.pending, key)
+	}
+}
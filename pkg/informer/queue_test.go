@@ -0,0 +1,107 @@
+package informer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPendingEventsCoalesce(t *testing.T) {
+	tests := []struct {
+		events []EventType
+		want   []EventType
+	}{
+		{[]EventType{EventAdd}, []EventType{EventAdd}},
+		{[]EventType{EventAdd, EventUpdate}, []EventType{EventAdd}},
+		{[]EventType{EventAdd, EventUpdate, EventDelete}, []EventType{}},
+		{[]EventType{EventSync, EventUpdate}, []EventType{EventSync}},
+		{[]EventType{EventSync, EventDelete}, []EventType{EventDelete}},
+		{[]EventType{EventUpdate, EventUpdate}, []EventType{EventUpdate}},
+		{[]EventType{EventUpdate, EventResync}, []EventType{EventUpdate}},
+		{[]EventType{EventResync, EventResync}, []EventType{EventResync}},
+		{[]EventType{EventResync, EventUpdate}, []EventType{EventUpdate}},
+		{[]EventType{EventUpdate, EventDelete}, []EventType{EventDelete}},
+		{[]EventType{EventDelete, EventDelete}, []EventType{EventDelete}},
+		{[]EventType{EventDelete, EventAdd}, []EventType{EventDelete, EventAdd}},
+		{[]EventType{EventDelete, EventAdd, EventUpdate}, []EventType{EventDelete, EventAdd}},
+		{[]EventType{EventDelete, EventAdd, EventDelete}, []EventType{EventDelete}},
+		{[]EventType{EventUpdate, EventDelete, EventAdd, EventUpdate}, []EventType{EventDelete, EventAdd}},
+	}
+	for _, test := range tests {
+		pending := pendingEvents{}
+		for _, event := range test.events {
+			pending = pending.coalesce(&pendingEvent{event: event})
+		}
+		got := []EventType{}
+		for _, e := range pending {
+			got = append(got, e.event)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("coalesce(%v) = %v, want %v", test.events, got, test.want)
+		}
+	}
+}
+
+func TestPendingEventsCoalesceKeepsFirstOldObject(t *testing.T) {
+	first, second := testObject("a", "x", "1", "10"), testObject("a", "x", "1", "11")
+	pending := pendingEvents{}.
+		coalesce(&pendingEvent{event: EventUpdate, oldObj: first}).
+		coalesce(&pendingEvent{event: EventUpdate, oldObj: second})
+	if len(pending) != 1 || pending[0].oldObj != first {
+		t.Errorf("coalesced updates kept %v, want the first old object", pending)
+	}
+	pending = pendingEvents{}.
+		coalesce(&pendingEvent{event: EventResync}).
+		coalesce(&pendingEvent{event: EventUpdate, oldObj: second})
+	if len(pending) != 1 || pending[0].oldObj != second {
+		t.Errorf("update after resync kept %v, want the old object of the update", pending)
+	}
+}
+
+func TestPendingEventsCoalesceHandledAdd(t *testing.T) {
+	pending := pendingEvents{}.
+		coalesce(&pendingEvent{event: EventAdd, handled: true}).
+		coalesce(&pendingEvent{event: EventUpdate}).
+		coalesce(&pendingEvent{event: EventDelete})
+	if len(pending) != 1 || pending[0].event != EventDelete {
+		t.Errorf("delete after a failed add kept %v, want the delete", pending)
+	}
+}
+
+func TestDebounceWait(t *testing.T) {
+	const second = time.Second
+	tests := []struct {
+		name            string
+		debounce        time.Duration
+		debounceMaxWait time.Duration
+		first, last     time.Duration
+		noTimes         bool
+		want            time.Duration
+		wantDebouncing  bool
+	}{
+		{name: "disabled", debounce: 0, want: 0},
+		{name: "not debouncing", debounce: 5 * second, debounceMaxWait: 50 * second, noTimes: true, want: 0},
+		{name: "quiet window", debounce: 5 * second, debounceMaxWait: 50 * second, first: -10 * second, last: -1 * second, want: 4 * second, wantDebouncing: true},
+		{name: "max wait", debounce: 5 * second, debounceMaxWait: 50 * second, first: -48 * second, last: 0, want: 2 * second, wantDebouncing: true},
+		{name: "quiet", debounce: 5 * second, debounceMaxWait: 50 * second, first: -6 * second, last: -6 * second, want: 0},
+		{name: "waited", debounce: 5 * second, debounceMaxWait: 50 * second, first: -51 * second, last: 0, want: 0},
+	}
+	key := objectKey{0, "a/x"}
+	for _, test := range tests {
+		i := &informer{
+			Opts:       Opts{Debounce: test.debounce, DebounceMaxWait: test.debounceMaxWait},
+			debouncing: map[objectKey]*debounceTimes{},
+		}
+		if !test.noTimes {
+			now := time.Now()
+			i.debouncing[key] = &debounceTimes{first: now.Add(test.first), last: now.Add(test.last)}
+		}
+		got := i.debounceWait(key)
+		if got > test.want || got < test.want-100*time.Millisecond {
+			t.Errorf("%s: debounceWait() = %v, want %v", test.name, got, test.want)
+		}
+		if _, debouncing := i.debouncing[key]; test.debounce > 0 && !test.noTimes && debouncing != test.wantDebouncing {
+			t.Errorf("%s: debouncing = %v after wait, want %v", test.name, debouncing, test.wantDebouncing)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package informer
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -13,21 +15,64 @@ func (i *informer) Run(ctx context.Context) (err error) {
 	logger, server := i.Logger, i.httpServer
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	defer i.queue.ShutDown()
-	for _, watch := range i.watches {
-		logger.Printf("watching %s", watch.name)
-		go watch.watcher.Run(ctx.Done())
+	var workers sync.WaitGroup
+	if i.Checkpoint != nil {
+		if err := i.loadCheckpoint(); err != nil {
+			return err
+		}
+		defer i.saveCheckpoint()
 	}
+	// workers in flight finish before the checkpoint is saved the last time
+	defer func() {
+		cancel()
+		i.queue.ShutDown()
+		workers.Wait()
+	}()
+	i.lock.Lock()
+	i.stopCh = ctx.Done()
 	for _, watch := range i.watches {
-		if !cache.WaitForCacheSync(ctx.Done(), watch.watcher.HasSynced) {
+		logger.Printf("watching %s", watch.name())
+		watch.run(ctx.Done())
+	}
+	i.lock.Unlock()
+	for _, watch := range i.listWatches() {
+		if !cache.WaitForCacheSync(ctx.Done(), watch.hasSynced) {
 			return fmt.Errorf("wait for caches to sync")
 		}
+		if watch.status().State != WatchPending {
+			watch.checkpointDeletes()
+		}
 	}
-	i.active = true
-	go wait.Until(func() {
-		for i.processNextItem(ctx) {
+	atomic.StoreInt32(&i.active, 1)
+	go wait.Until(i.resolveWatches, i.ResolvePeriod, ctx.Done())
+	if i.Checkpoint != nil {
+		go wait.Until(i.saveCheckpoint, i.CheckpointPeriod, ctx.Done())
+	}
+	if i.BatchHandler != nil && i.BatchSize > 0 {
+		items := i.feedBatches(ctx)
+		for worker := 0; worker < i.Workers; worker++ {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				wait.Until(func() {
+					for i.processNextBatch(ctx, items) {
+					}
+				}, time.Second, ctx.Done())
+			}()
+		}
+	} else {
+		for worker := 0; worker < i.Workers; worker++ {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				// objects left in the queue once stopped are not handled, they are listed again on restart
+				wait.Until(func() {
+					for ctx.Err() == nil && i.processNextItem(ctx) {
+					}
+				}, time.Second, ctx.Done())
+			}()
 		}
-	}, time.Second, ctx.Done())
+	}
 
 	if server != nil {
 		go func() {
@@ -45,7 +90,7 @@ func (i *informer) Run(ctx context.Context) (err error) {
 	} else {
 		<-ctx.Done()
 	}
-	i.active = false
+	atomic.StoreInt32(&i.active, 0)
 	logger.Printf("stopped all watch")
 	return
 }
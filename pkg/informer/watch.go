@@ -0,0 +1,364 @@
+package informer
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamic "k8s.io/client-go/deprecated-dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watchReflector lists and watches one namespace of a watch, all reflectors of a watch share the same indexer
+type watchReflector struct {
+	namespace  string
+	controller cache.Controller
+	// initial reflectors are created before the informer becomes active, they deliver sync events for the initial list
+	initial, synced bool
+	started         bool
+	stop, done      chan struct{}
+}
+
+// namespacedKeys limits the known objects of a reflector to its own namespace,
+// so that a relist of one namespace never deletes objects of the others
+type namespacedKeys struct {
+	cache.Indexer
+	namespace string
+}
+
+func (n *namespacedKeys) ListKeys() []string {
+	keys := n.Indexer.ListKeys()
+	if n.namespace == metav1.NamespaceAll {
+		return keys
+	}
+	ret, prefix := []string{}, n.namespace+"/"
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			ret = append(ret, key)
+		}
+	}
+	return ret
+}
+
+func watchNamespaces(namespaces []string) []string {
+	ret, seen := []string{}, map[string]bool{}
+	for _, namespace := range namespaces {
+		if namespace == metav1.NamespaceAll {
+			return []string{metav1.NamespaceAll}
+		}
+		if !seen[namespace] {
+			ret, seen[namespace] = append(ret, namespace), true
+		}
+	}
+	if len(ret) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return ret
+}
+
+func (w *informerWatch) newReflector(namespace string) *watchReflector {
+	reflector := &watchReflector{
+		namespace: namespace,
+		initial:   !w.informer.Active(),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	reflector.controller = cache.New(&cache.Config{
+		Queue:            cache.NewDeltaFIFO(cache.MetaNamespaceKeyFunc, &namespacedKeys{w.indexer, namespace}),
+		ListerWatcher:    w.listWatch(namespace),
+		ObjectType:       &unstructured.Unstructured{},
+		FullResyncPeriod: w.Resync,
+		Process: func(obj interface{}) error {
+			err := w.process(obj, reflector.initial && !reflector.synced)
+			reflector.synced = reflector.controller.HasSynced()
+			return err
+		},
+	})
+	return reflector
+}
+
+func (r *watchReflector) run(stopCh <-chan struct{}) {
+	r.started = true
+	reflectorStopCh := make(chan struct{})
+	go func() {
+		select {
+		case <-stopCh:
+		case <-r.stop:
+		}
+		close(reflectorStopCh)
+	}()
+	go func() {
+		defer close(r.done)
+		r.controller.Run(reflectorStopCh)
+	}()
+}
+
+// shutdown stops the reflector and waits for the processing to finish
+func (r *watchReflector) shutdown() {
+	if r.started {
+		close(r.stop)
+		<-r.done
+	}
+}
+
+// start resolves the kind of the watch, and starts reflectors if the watch is running
+func (w *informerWatch) start() error {
+	client, resource, err := w.informer.client.DynamicClient(w.APIVersion, w.Kind)
+	if err != nil {
+		return err
+	}
+	namespaces, namespaceSelector := watchNamespaces(w.Namespaces), w.NamespaceSelector
+	if !resource.Namespaced {
+		namespaces, namespaceSelector = []string{metav1.NamespaceAll}, ""
+	}
+	watchNamespace := strings.Join(namespaces, ",")
+	if namespaceSelector != "" {
+		watchNamespace = fmt.Sprintf("(%s)", namespaceSelector)
+	}
+	listClient := client
+	if w.MetadataOnly {
+		if listClient, _, err = w.informer.client.MetadataClient(w.APIVersion, w.Kind); err != nil {
+			return err
+		}
+	}
+	w.lock.Lock()
+	w.resolvedName.Store(fmt.Sprintf("%s/%s %s %s", watchNamespace, resource.Name, w.LabelSelector, w.FieldSelector))
+	w.listWatch = func(namespace string) cache.ListerWatcher {
+		listWatch := newListWatcherFromResourceClient(listClient.Resource(resource, namespace), w.LabelSelector, w.FieldSelector, w.transform)
+		if w.WaitForKind {
+			list := listWatch.ListFunc
+			listWatch.ListFunc = func(options metav1.ListOptions) (runtime.Object, error) {
+				ret, err := list(options)
+				if errors.IsNotFound(err) {
+					atomic.StoreInt32(&w.informer.kindsChanged, 1)
+				}
+				return ret, err
+			}
+		}
+		return listWatch
+	}
+	w.resourceClient = func(namespace string) dynamic.ResourceInterface {
+		return client.Resource(resource, namespace)
+	}
+	w.lock.Unlock()
+	if namespaceSelector != "" {
+		return w.watchNamespaceSelector(namespaceSelector)
+	}
+	for _, namespace := range namespaces {
+		w.addReflector(namespace)
+	}
+	return nil
+}
+
+func (w *informerWatch) name() string {
+	return w.resolvedName.Load().(string)
+}
+
+// transform runs before objects are stored, objects are left as is if the transform fails
+func (w *informerWatch) transform(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if w.MetadataOnly {
+		obj = metadataOnly(obj, w.APIVersion, w.Kind)
+	}
+	if w.Transform != nil {
+		ret, err := w.Transform(obj)
+		if err != nil {
+			w.informer.Logger.Printf("failed to transform %s/%s of %s: %v", obj.GetNamespace(), obj.GetName(), w.name(), err)
+			return obj
+		}
+		return ret
+	}
+	return obj
+}
+
+// metadataOnly strips objects to apiVersion, kind and metadata, PartialObjectMetadata turns into the watched kind
+func metadataOnly(obj *unstructured.Unstructured, apiVersion, kind string) *unstructured.Unstructured {
+	ret := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if metadata, ok := obj.Object["metadata"]; ok {
+		ret.Object["metadata"] = metadata
+	}
+	ret.SetAPIVersion(apiVersion)
+	ret.SetKind(kind)
+	return ret
+}
+
+// fetchObject gets the full object of the cached one, returns nil if not found
+func (w *informerWatch) fetchObject(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	w.lock.Lock()
+	resourceClient := w.resourceClient
+	w.lock.Unlock()
+	if resourceClient == nil {
+		return nil, fmt.Errorf("kind of %s not available", w.name())
+	}
+	ret, err := resourceClient(obj.GetNamespace()).Get(obj.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return ret, err
+}
+
+// stop stops all reflectors and drops the objects left like removeReflector, the watch turns to pending
+func (w *informerWatch) stop() {
+	w.lock.Lock()
+	tracker, namespaces := w.namespaceTracker, []string{}
+	w.namespaceTracker, w.listWatch, w.resourceClient = nil, nil, nil
+	for namespace := range w.reflectors {
+		namespaces = append(namespaces, namespace)
+	}
+	w.lock.Unlock()
+	if tracker != nil {
+		tracker.shutdown()
+	}
+	for _, namespace := range namespaces {
+		w.removeReflector(namespace)
+	}
+}
+
+func (w *informerWatch) status() WatchStatus {
+	state := WatchActive
+	w.lock.Lock()
+	pending := w.listWatch == nil
+	w.lock.Unlock()
+	if pending {
+		state = WatchPending
+	} else if !w.hasSynced() {
+		state = WatchSyncing
+	}
+	return WatchStatus{Index: w.index, Name: w.name(), State: state}
+}
+
+// addReflector starts watching the namespace, immediately if the watch is running
+func (w *informerWatch) addReflector(namespace string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if _, ok := w.reflectors[namespace]; ok {
+		return
+	}
+	reflector := w.newReflector(namespace)
+	w.reflectors[namespace] = reflector
+	if w.stopCh != nil {
+		w.informer.Logger.Printf("watching %s in namespace %s", w.name(), namespace)
+		reflector.run(w.stopCh)
+	}
+}
+
+// removeReflector stops watching the namespace, and emits delete events for objects left in it,
+// except with a finalizer, where objects no longer watched keep it and are only dropped from the cache
+func (w *informerWatch) removeReflector(namespace string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	reflector, ok := w.reflectors[namespace]
+	if !ok {
+		return
+	}
+	delete(w.reflectors, namespace)
+	if reflector.started {
+		w.informer.Logger.Printf("stop watching %s in namespace %s", w.name(), namespace)
+		reflector.shutdown()
+	}
+	keys := &namespacedKeys{w.indexer, namespace}
+	for _, key := range keys.ListKeys() {
+		if obj, exists, err := w.indexer.GetByKey(key); err == nil && exists {
+			if err := w.indexer.Delete(obj); err == nil && w.informer.Finalizer == "" {
+				w.handleDelete(obj)
+			}
+		}
+	}
+}
+
+func (w *informerWatch) run(stopCh <-chan struct{}) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.stopCh = stopCh
+	for _, reflector := range w.reflectors {
+		reflector.run(stopCh)
+	}
+	if w.namespaceTracker != nil {
+		w.namespaceTracker.run(stopCh)
+	}
+}
+
+// hasSynced returns true if all reflectors have synced, or the watch is pending
+func (w *informerWatch) hasSynced() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.namespaceTracker != nil && !w.namespaceTracker.controller.HasSynced() {
+		return false
+	}
+	for _, reflector := range w.reflectors {
+		if !reflector.controller.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// watchNamespaceSelector tracks namespaces matching the label selector, reflectors are added
+// or removed as namespaces gain or lose the labels
+func (w *informerWatch) watchNamespaceSelector(namespaceSelector string) error {
+	client, resource, err := w.informer.client.DynamicClient("v1", "Namespace")
+	if err != nil {
+		return fmt.Errorf("failed to watch namespaces: %v", err)
+	}
+	namespaceName := func(obj interface{}) string {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			_, name, _ := cache.SplitMetaNamespaceKey(tombstone.Key)
+			return name
+		}
+		return obj.(*unstructured.Unstructured).GetName()
+	}
+	_, controller := cache.NewInformer(
+		newListWatcherFromResourceClient(client.Resource(resource, metav1.NamespaceAll), namespaceSelector, "", nil),
+		&unstructured.Unstructured{},
+		0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				w.addReflector(namespaceName(obj))
+			},
+			DeleteFunc: func(obj interface{}) {
+				w.removeReflector(namespaceName(obj))
+			},
+		},
+	)
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.namespaceTracker = &watchReflector{controller: controller, stop: make(chan struct{}), done: make(chan struct{})}
+	if w.stopCh != nil {
+		w.namespaceTracker.run(w.stopCh)
+	}
+	return nil
+}
+
+// process stores deltas into the shared indexer, like cache.NewIndexerInformer does,
+// objects of the initial list are delivered as sync events instead of add
+func (w *informerWatch) process(obj interface{}, initialList bool) error {
+	for _, d := range obj.(cache.Deltas) {
+		switch d.Type {
+		case cache.Sync, cache.Added, cache.Updated:
+			if old, exists, err := w.indexer.Get(d.Object); err == nil && exists {
+				if err := w.indexer.Update(d.Object); err != nil {
+					return err
+				}
+				w.handleUpdate(old, d.Object)
+			} else {
+				if err := w.indexer.Add(d.Object); err != nil {
+					return err
+				}
+				if initialList && d.Type == cache.Sync {
+					w.handleSync(d.Object)
+				} else {
+					w.handleAdd(d.Object)
+				}
+			}
+		case cache.Deleted:
+			if err := w.indexer.Delete(d.Object); err != nil {
+				return err
+			}
+			w.handleDelete(d.Object)
+		}
+	}
+	return nil
+}
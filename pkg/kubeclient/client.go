@@ -3,6 +3,7 @@ package kubeclient
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,10 +13,14 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/discovery/cached"
 	"k8s.io/client-go/restmapper"
 
@@ -39,11 +44,18 @@ type Client interface {
 	GetConfig() (*rest.Config, error)
 	GetConfigOrDie() *rest.Config
 	Namespace() string
+	Namespaces() []string
 	DefaultNamespace() string
 	DynamicClientPool() dynamic.ClientPool
 	APIResource(apiVersion, kind string) (*metav1.APIResource, *schema.GroupVersionKind, error)
+	ResolveResources(resource string) ([]schema.GroupVersionKind, error)
+	GroupKinds(group, version string) ([]schema.GroupVersionKind, error)
+	ResetRESTMapper()
 	DynamicClient(apiVersion, kind string) (client dynamic.Interface, resource *metav1.APIResource, err error)
+	MetadataClient(apiVersion, kind string) (client dynamic.Interface, resource *metav1.APIResource, err error)
 	ResourceClient(apiVersion, kind string) (client dynamic.ResourceInterface, resource *metav1.APIResource, namespace string, err error)
+	PatchObject(obj *unstructured.Unstructured, patchType types.PatchType, data []byte) (*unstructured.Unstructured, error)
+	RecordEvent(obj *unstructured.Unstructured, eventType, reason, message string) error
 }
 
 //NewClient func
@@ -56,8 +68,12 @@ type client struct {
 	clientConfigOnce sync.Once
 	clientConfig     clientcmd.ClientConfig
 	dynamicOnce      sync.Once
+	discovery        discovery.CachedDiscoveryInterface
+	serverDiscovery  discovery.DiscoveryInterface
 	restMapper       *restmapper.DeferredDiscoveryRESTMapper
 	dynamicPool      dynamic.ClientPool
+	metadataOnce     sync.Once
+	metadataPool     dynamic.ClientPool
 }
 
 //BindFlags func
@@ -77,7 +93,7 @@ func (c *client) BindFlags(flags *pflag.FlagSet, envPrefix string) {
 	}
 	flags.StringVar(&c.KubeConfigPath, "kubeconfig", c.KubeConfigPath, "path to the kubeconfig file")
 	flags.StringVarP(&c.MasterURL, "server", "s", os.Getenv(envPrefix+"SERVER"), "URL of the Kubernetes API server")
-	flags.StringVarP(&c.ClientOpts.Namespace, "namespace", "n", os.Getenv(envPrefix+"NAMESPACE"), "namespace")
+	flags.StringVarP(&c.ClientOpts.Namespace, "namespace", "n", os.Getenv(envPrefix+"NAMESPACE"), "namespace, or comma separated namespace list")
 	if !c.DisableAllNamespaces {
 		flags.BoolVar(&c.AllNamespaces, "all-namespaces", os.Getenv(envPrefix+"ALL_NAMESPACES") != "", "all namespaces")
 	}
@@ -92,14 +108,25 @@ func (c *client) ClientConfig() clientcmd.ClientConfig {
 	return c.clientConfig
 }
 
+// Namespace is the first of Namespaces(), use Namespaces() where a namespace list is allowed
 func (c *client) Namespace() string {
+	return c.Namespaces()[0]
+}
+
+func (c *client) Namespaces() []string {
 	if c.AllNamespaces {
-		return metav1.NamespaceAll
+		return []string{metav1.NamespaceAll}
+	}
+	namespaces := []string{}
+	for _, ns := range strings.Split(c.ClientOpts.Namespace, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
 	}
-	if !c.AllNamespaces && c.ClientOpts.Namespace == "" {
-		return c.DefaultNamespace()
+	if len(namespaces) == 0 {
+		return []string{c.DefaultNamespace()}
 	}
-	return c.ClientOpts.Namespace
+	return namespaces
 }
 
 func (c *client) DefaultNamespace() string {
@@ -130,9 +157,11 @@ func (c *client) DynamicClientPool() dynamic.ClientPool {
 	c.dynamicOnce.Do(func() {
 		config := c.GetConfigOrDie()
 		config.ContentConfig = dynamic.ContentConfig()
-		restMapper := restmapper.NewDeferredDiscoveryRESTMapper(cached.NewMemCacheClient(clientset.NewForConfigOrDie(config).Discovery()))
+		serverDiscovery := clientset.NewForConfigOrDie(config).Discovery()
+		discovery := cached.NewMemCacheClient(serverDiscovery)
+		restMapper := restmapper.NewDeferredDiscoveryRESTMapper(discovery)
 		restMapper.Reset()
-		c.restMapper, c.dynamicPool = restMapper, dynamic.NewClientPool(config, restMapper, dynamic.LegacyAPIPathResolverFunc)
+		c.discovery, c.serverDiscovery, c.restMapper, c.dynamicPool = discovery, serverDiscovery, restMapper, dynamic.NewClientPool(config, restMapper, dynamic.LegacyAPIPathResolverFunc)
 	})
 	return c.dynamicPool
 }
@@ -150,7 +179,7 @@ func (c *client) APIResource(apiVersion, kind string) (*metav1.APIResource, *sch
 	}
 	mapping, err := c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get the resource REST mapping for GroupVersionKind(%s): %v", gvk.String(), err)
+		return nil, nil, &mappingError{gvk, err}
 	}
 	return &metav1.APIResource{
 		Name:       mapping.Resource.Resource,
@@ -159,6 +188,170 @@ func (c *client) APIResource(apiVersion, kind string) (*metav1.APIResource, *sch
 	}, &gvk, nil
 }
 
+type mappingError struct {
+	gvk schema.GroupVersionKind
+	err error
+}
+
+func (e *mappingError) Error() string {
+	return fmt.Sprintf("failed to get the resource REST mapping for GroupVersionKind(%s): %v", e.gvk.String(), e.err)
+}
+
+type resourceError struct {
+	resource string
+}
+
+func (e *resourceError) Error() string {
+	return fmt.Sprintf("the server doesn't have a resource type %q", e.resource)
+}
+
+//IsNoMatchError returns true if the kind or resource name is not (or no longer) served by the api server
+func IsNoMatchError(err error) bool {
+	if _, ok := err.(*resourceError); ok {
+		return true
+	}
+	if e, ok := err.(*mappingError); ok {
+		err = e.err
+	}
+	return meta.IsNoMatchError(err)
+}
+
+//ResetRESTMapper drops cached discovery, so that kinds installed or removed later are noticed
+func (c *client) ResetRESTMapper() {
+	c.DynamicClientPool()
+	c.restMapper.Reset()
+}
+
+// ResolveResources resolves kubectl style resource names, eg. `deployments.apps`, `deploy`, `deployments.v1.apps`, `pods/v1`,
+// or categories such as `all`, into kinds of listable and watchable resources
+func (c *client) ResolveResources(resource string) ([]schema.GroupVersionKind, error) {
+	c.DynamicClientPool()
+	name, version := resource, ""
+	if i := strings.Index(resource, "/"); i >= 0 {
+		name, version = resource[:i], resource[i+1:]
+	}
+	preferred, err := c.discovery.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to discover preferred resources: %v", err)
+	}
+	all, err := c.discovery.ServerResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to discover resources: %v", err)
+	}
+	preferredVersions := map[schema.GroupKind]string{}
+	for _, list := range preferred {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			preferredVersions[schema.GroupKind{Group: gv.Group, Kind: r.Kind}] = gv.Version
+		}
+	}
+	byName, byCategory, seen := []schema.GroupVersionKind{}, []schema.GroupVersionKind{}, map[schema.GroupVersionKind]bool{}
+	for _, list := range all {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil || (version != "" && gv.Version != version) {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") || !containsAll(r.Verbs, "list", "watch") {
+				continue
+			}
+			gvk := gv.WithKind(r.Kind)
+			if seen[gvk] {
+				continue
+			}
+			isPreferred := version != "" || preferredVersions[gvk.GroupKind()] == gv.Version
+			switch {
+			case matchResourceName(name, &r, gv, isPreferred):
+				byName, seen[gvk] = append(byName, gvk), true
+			case isPreferred && containsAll(r.Categories, name):
+				byCategory, seen[gvk] = append(byCategory, gvk), true
+			}
+		}
+	}
+	if len(byName) > 0 {
+		// like kubectl, the first group in discovery order wins
+		return byName[:1], nil
+	}
+	if len(byCategory) > 0 {
+		return byCategory, nil
+	}
+	return nil, &resourceError{resource}
+}
+
+// GroupKinds discovers kinds of listable and watchable resources in the api group,
+// in the preferred versions if version is empty. Discovery of the group is not cached,
+// so that kinds installed later are noticed without resetting the RESTMapper
+func (c *client) GroupKinds(group, version string) ([]schema.GroupVersionKind, error) {
+	c.DynamicClientPool()
+	groups, err := c.serverDiscovery.ServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover groups: %v", err)
+	}
+	kinds, seen := []schema.GroupVersionKind{}, map[string]bool{}
+	for _, g := range groups.Groups {
+		if g.Name != group {
+			continue
+		}
+		// like ServerPreferredResources, a resource is in the preferred version, or else the first version serving it
+		versions, fetched := g.Versions, map[string]bool{}
+		if version == "" {
+			versions = append([]metav1.GroupVersionForDiscovery{g.PreferredVersion}, g.Versions...)
+		}
+		for _, v := range versions {
+			if version != "" && v.Version != version || fetched[v.Version] {
+				continue
+			}
+			fetched[v.Version] = true
+			list, err := c.serverDiscovery.ServerResourcesForGroupVersion(v.GroupVersion)
+			if err != nil {
+				return nil, fmt.Errorf("failed to discover resources of %s: %v", v.GroupVersion, err)
+			}
+			for _, r := range list.APIResources {
+				if strings.Contains(r.Name, "/") || !containsAll(r.Verbs, "list", "watch") || seen[r.Name] {
+					continue
+				}
+				kinds, seen[r.Name] = append(kinds, schema.GroupVersionKind{Group: group, Version: v.Version, Kind: r.Kind}), true
+			}
+		}
+	}
+	return kinds, nil
+}
+
+func containsAll(list []string, required ...string) bool {
+	for _, r := range required {
+		found := false
+		for _, item := range list {
+			if item == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func matchResourceName(name string, r *metav1.APIResource, gv schema.GroupVersion, preferred bool) bool {
+	names := append([]string{r.Name, r.SingularName, strings.ToLower(r.Kind)}, r.ShortNames...)
+	for _, n := range names {
+		if n == "" {
+			continue
+		}
+		if gv.Group == "" && name == n+"."+gv.Version || gv.Group != "" && name == n+"."+gv.Version+"."+gv.Group {
+			return true
+		}
+		if preferred && (name == n || (gv.Group != "" && name == n+"."+gv.Group)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *client) DynamicClient(apiVersion, kind string) (dynamic.Interface, *metav1.APIResource, error) {
 	resource, gvk, err := c.APIResource(apiVersion, kind)
 	if err != nil {
@@ -171,15 +364,116 @@ func (c *client) DynamicClient(apiVersion, kind string) (dynamic.Interface, *met
 	return client, resource, nil
 }
 
+// MetadataClient returns a client to list and watch PartialObjectMetadata of the kind,
+// the api server falls back to full objects if it does not support PartialObjectMetadata
+func (c *client) MetadataClient(apiVersion, kind string) (dynamic.Interface, *metav1.APIResource, error) {
+	resource, gvk, err := c.APIResource(apiVersion, kind)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get resource type: %v", err)
+	}
+	c.metadataOnce.Do(func() {
+		config := c.GetConfigOrDie()
+		config.ContentConfig = dynamic.ContentConfig()
+		wrapTransport := config.WrapTransport
+		config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+			if wrapTransport != nil {
+				rt = wrapTransport(rt)
+			}
+			return &metadataRoundTripper{rt}
+		}
+		c.metadataPool = dynamic.NewClientPool(config, c.restMapper, dynamic.LegacyAPIPathResolverFunc)
+	})
+	client, err := c.metadataPool.ClientForGroupVersionKind(*gvk)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get client for GroupVersionKind(%s): %v", gvk.String(), err)
+	}
+	return client, resource, nil
+}
+
+const (
+	acceptPartialObjectMetadata     = "application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1beta1,application/json"
+	acceptPartialObjectMetadataList = "application/json;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1,application/json;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1beta1,application/json"
+)
+
+// metadataRoundTripper asks for PartialObjectMetadata, it only serves list and watch requests
+type metadataRoundTripper struct {
+	rt http.RoundTripper
+}
+
+func (m *metadataRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" {
+		return m.rt.RoundTrip(req)
+	}
+	req = utilnet.CloneRequest(req)
+	if req.URL.Query().Get("watch") == "true" {
+		req.Header.Set("Accept", acceptPartialObjectMetadata)
+	} else {
+		req.Header.Set("Accept", acceptPartialObjectMetadataList)
+	}
+	return m.rt.RoundTrip(req)
+}
+
 func (c *client) ResourceClient(apiVersion, kind string) (dynamic.ResourceInterface, *metav1.APIResource, string, error) {
 	client, resource, err := c.DynamicClient(apiVersion, kind)
 	if err != nil {
 		return nil, nil, "", err
 	}
-	namespace := c.Namespace()
-	if !resource.Namespaced {
-		namespace = metav1.NamespaceAll
+	namespace := metav1.NamespaceAll
+	if resource.Namespaced {
+		namespaces := c.Namespaces()
+		if len(namespaces) > 1 {
+			return nil, nil, "", fmt.Errorf("single namespace required for %s, got %s", resource.Name, strings.Join(namespaces, ","))
+		}
+		namespace = namespaces[0]
 	}
 	return client.Resource(resource, namespace), resource, namespace, nil
 
 }
+
+//PatchObject patches the object with its apiVersion, kind, namespace and name
+func (c *client) PatchObject(obj *unstructured.Unstructured, patchType types.PatchType, data []byte) (*unstructured.Unstructured, error) {
+	client, resource, err := c.DynamicClient(obj.GetAPIVersion(), obj.GetKind())
+	if err != nil {
+		return nil, err
+	}
+	return client.Resource(resource, obj.GetNamespace()).Patch(obj.GetName(), patchType, data)
+}
+
+//RecordEvent creates an Event of the object, in the default namespace for objects not namespaced
+func (c *client) RecordEvent(obj *unstructured.Unstructured, eventType, reason, message string) error {
+	client, resource, err := c.DynamicClient("v1", "Event")
+	if err != nil {
+		return err
+	}
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	now := metav1.Now()
+	timestamp, _ := now.MarshalQueryParameter()
+	event := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"metadata": map[string]interface{}{
+			"generateName": obj.GetName() + ".",
+			"namespace":    namespace,
+		},
+		"involvedObject": map[string]interface{}{
+			"apiVersion":      obj.GetAPIVersion(),
+			"kind":            obj.GetKind(),
+			"name":            obj.GetName(),
+			"namespace":       obj.GetNamespace(),
+			"uid":             string(obj.GetUID()),
+			"resourceVersion": obj.GetResourceVersion(),
+		},
+		"type":           eventType,
+		"reason":         reason,
+		"message":        message,
+		"source":         map[string]interface{}{"component": "kube-informer"},
+		"firstTimestamp": timestamp,
+		"lastTimestamp":  timestamp,
+		"count":          int64(1),
+	}}
+	_, err = client.Resource(resource, namespace).Create(event)
+	return err
+}
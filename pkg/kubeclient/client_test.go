@@ -0,0 +1,187 @@
+package kubeclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var listWatch = metav1.Verbs{"get", "list", "watch"}
+
+var discoveryDocs = map[string]interface{}{
+	"/api": &metav1.APIVersions{
+		TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+		Versions: []string{"v1"},
+	},
+	"/apis": &metav1.APIGroupList{
+		TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+		Groups: []metav1.APIGroup{
+			apiGroup("apps", "v1", "v1beta1"),
+			apiGroup("extensions", "v1beta1"),
+			apiGroup("example.com", "v1"),
+		},
+	},
+	"/api/v1": apiResources("v1",
+		metav1.APIResource{Name: "pods", SingularName: "pod", Kind: "Pod", Namespaced: true, ShortNames: []string{"po"}, Categories: []string{"all"}, Verbs: listWatch},
+		metav1.APIResource{Name: "pods/log", Kind: "Pod", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+		metav1.APIResource{Name: "services", SingularName: "service", Kind: "Service", Namespaced: true, ShortNames: []string{"svc"}, Categories: []string{"all"}, Verbs: listWatch},
+		metav1.APIResource{Name: "bindings", Kind: "Binding", Namespaced: true, Verbs: metav1.Verbs{"create"}},
+	),
+	"/apis/apps/v1": apiResources("apps/v1",
+		metav1.APIResource{Name: "deployments", SingularName: "deployment", Kind: "Deployment", Namespaced: true, ShortNames: []string{"deploy"}, Categories: []string{"all"}, Verbs: listWatch},
+	),
+	"/apis/apps/v1beta1": apiResources("apps/v1beta1",
+		metav1.APIResource{Name: "deployments", SingularName: "deployment", Kind: "Deployment", Namespaced: true, ShortNames: []string{"deploy"}, Categories: []string{"all"}, Verbs: listWatch},
+	),
+	"/apis/extensions/v1beta1": apiResources("extensions/v1beta1",
+		metav1.APIResource{Name: "deployments", SingularName: "deployment", Kind: "Deployment", Namespaced: true, ShortNames: []string{"deploy"}, Verbs: listWatch},
+		metav1.APIResource{Name: "ingresses", SingularName: "ingress", Kind: "Ingress", Namespaced: true, ShortNames: []string{"ing"}, Verbs: listWatch},
+	),
+	"/apis/example.com/v1": apiResources("example.com/v1",
+		metav1.APIResource{Name: "widgets", SingularName: "widget", Kind: "Widget", Categories: []string{"all", "example"}, Verbs: listWatch},
+	),
+}
+
+func apiGroup(name string, versions ...string) metav1.APIGroup {
+	group := metav1.APIGroup{Name: name}
+	for _, version := range versions {
+		group.Versions = append(group.Versions, metav1.GroupVersionForDiscovery{GroupVersion: name + "/" + version, Version: version})
+	}
+	group.PreferredVersion = group.Versions[0]
+	return group
+}
+
+func apiResources(groupVersion string, resources ...metav1.APIResource) *metav1.APIResourceList {
+	return &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: groupVersion,
+		APIResources: resources,
+	}
+}
+
+func newDiscoveryClient() (*client, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, ok := discoveryDocs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	}))
+	return NewClient(&ClientOpts{MasterURL: server.URL}).(*client), server.Close
+}
+
+func TestResolveResources(t *testing.T) {
+	c, done := newDiscoveryClient()
+	defer done()
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	service := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	deploymentV1beta1 := schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"}
+	extensionsDeployment := schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Deployment"}
+	widget := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	tests := []struct {
+		resource string
+		want     []schema.GroupVersionKind
+	}{
+		{"pods", []schema.GroupVersionKind{pod}},
+		{"pod", []schema.GroupVersionKind{pod}},
+		{"po", []schema.GroupVersionKind{pod}},
+		{"Pod", nil},
+		{"pods.v1", []schema.GroupVersionKind{pod}},
+		{"svc", []schema.GroupVersionKind{service}},
+		{"deployments", []schema.GroupVersionKind{deployment}},
+		{"deploy.extensions", []schema.GroupVersionKind{extensionsDeployment}},
+		{"deployments.v1beta1.apps", []schema.GroupVersionKind{deploymentV1beta1}},
+		{"deployments/v1beta1", []schema.GroupVersionKind{deploymentV1beta1}},
+		{"deployments/v2", nil},
+		{"widgets.example.com", []schema.GroupVersionKind{widget}},
+		{"all", []schema.GroupVersionKind{pod, service, deployment, widget}},
+		{"example", []schema.GroupVersionKind{widget}},
+		{"bindings", nil},
+		{"unknown", nil},
+	}
+	for _, test := range tests {
+		got, err := c.ResolveResources(test.resource)
+		if test.want == nil {
+			if !IsNoMatchError(err) {
+				t.Errorf("ResolveResources(%q) = %v, %v, want no match error", test.resource, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ResolveResources(%q) failed: %v", test.resource, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ResolveResources(%q) = %v, want %v", test.resource, got, test.want)
+		}
+	}
+}
+
+func TestGroupKinds(t *testing.T) {
+	c, done := newDiscoveryClient()
+	defer done()
+	tests := []struct {
+		group, version string
+		want           []schema.GroupVersionKind
+	}{
+		{"", "", []schema.GroupVersionKind{{Version: "v1", Kind: "Pod"}, {Version: "v1", Kind: "Service"}}},
+		{"apps", "", []schema.GroupVersionKind{{Group: "apps", Version: "v1", Kind: "Deployment"}}},
+		{"apps", "v1beta1", []schema.GroupVersionKind{{Group: "apps", Version: "v1beta1", Kind: "Deployment"}}},
+		{"apps", "v2", []schema.GroupVersionKind{}},
+		{"extensions", "", []schema.GroupVersionKind{{Group: "extensions", Version: "v1beta1", Kind: "Deployment"}, {Group: "extensions", Version: "v1beta1", Kind: "Ingress"}}},
+		{"missing.example.com", "", []schema.GroupVersionKind{}},
+	}
+	for _, test := range tests {
+		got, err := c.GroupKinds(test.group, test.version)
+		if err != nil {
+			t.Errorf("GroupKinds(%q, %q) failed: %v", test.group, test.version, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("GroupKinds(%q, %q) = %v, want %v", test.group, test.version, got, test.want)
+		}
+	}
+}
+
+func TestMatchResourceName(t *testing.T) {
+	core := schema.GroupVersion{Version: "v1"}
+	apps := schema.GroupVersion{Group: "apps", Version: "v1"}
+	pods := &metav1.APIResource{Name: "pods", SingularName: "pod", Kind: "Pod", ShortNames: []string{"po"}}
+	deployments := &metav1.APIResource{Name: "deployments", Kind: "Deployment", ShortNames: []string{"deploy"}}
+	tests := []struct {
+		name      string
+		resource  *metav1.APIResource
+		gv        schema.GroupVersion
+		preferred bool
+		want      bool
+	}{
+		{"pods", pods, core, true, true},
+		{"pods", pods, core, false, false},
+		{"pod", pods, core, true, true},
+		{"po", pods, core, true, true},
+		{"pods.v1", pods, core, false, true},
+		{"pods.v1.", pods, core, false, false},
+		{"pods.apps", pods, core, true, false},
+		{"deployments", deployments, apps, true, true},
+		{"deployment", deployments, apps, true, true},
+		{"deployments.apps", deployments, apps, true, true},
+		{"deployments.apps", deployments, apps, false, false},
+		{"deploy.v1.apps", deployments, apps, false, true},
+		{"deployments.v1", deployments, apps, true, false},
+		{"deployments.v2.apps", deployments, apps, true, false},
+		{"", &metav1.APIResource{Name: "pods", Kind: "Pod"}, core, true, false},
+	}
+	for _, test := range tests {
+		if got := matchResourceName(test.name, test.resource, test.gv, test.preferred); got != test.want {
+			t.Errorf("matchResourceName(%q, %s, %s, %v) = %v, want %v", test.name, test.resource.Name, test.gv, test.preferred, got, test.want)
+		}
+	}
+}